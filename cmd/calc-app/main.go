@@ -29,7 +29,11 @@ func main() {
 	}
 
 	r := repository.New(store)
-	s := service.New(r)
+	reg, err := service.NewRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := service.New(r, repository.NewRates(store), reg, &cfg.Calculator)
 	t := transport.New(s, l)
 
 	e := echo.New()
@@ -79,6 +79,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/resttransport.CalcRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "example": "ru-RU",
+                        "description": "Локаль formatted, если в теле нет locale; на разбор выражения не влияет",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -89,7 +96,84 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ParseErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "выражение длиннее CALC_MAX_LENGTH",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "ошибка вычисления или слишком сложное выражение",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calculations/explain": {
+            "post": {
+                "description": "Вычисляет выражение без сохранения и возвращает шаги в порядке выполнения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calculations"
+                ],
+                "summary": "Шаги вычисления выражения",
+                "parameters": [
+                    {
+                        "description": "Данные для вычисления",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.CalcRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "example": "ru-RU",
+                        "description": "Локаль formatted, если в теле нет locale; на разбор выражения не влияет",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ExplainResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ParseErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "выражение длиннее CALC_MAX_LENGTH",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "ошибка вычисления или слишком сложное выражение",
                         "schema": {
                             "$ref": "#/definitions/resttransport.ErrorResponse"
                         }
@@ -216,6 +300,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "ru-RU",
+                        "description": "Локаль formatted, если в теле нет locale; на разбор выражения не влияет",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -225,12 +316,270 @@ const docTemplate = `{
                             "$ref": "#/definitions/resttransport.CalcResponse"
                         }
                     },
+                    "400": {
+                        "description": "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ParseErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "выражение длиннее CALC_MAX_LENGTH",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "ошибка вычисления или слишком сложное выражение",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calculations/{id}/explain": {
+            "get": {
+                "description": "Повторяет сохранённое вычисление и возвращает его шаги в порядке выполнения. Индентификатор - строковой тип UUID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calculations"
+                ],
+                "summary": "Шаги вычисления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Индентификатор",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "ru-RU",
+                        "description": "Локаль formatted, если вычисление записано без локали",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ExplainResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rates": {
+            "get": {
+                "description": "Возвращает последнюю загруженную таблицу курсов, по ней считаются новые выражения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Текущие курсы валют",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.RatesResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "курсы не загружены",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Создаёт новую таблицу курсов; прежние сохраняются для уже посчитанных выражений. Нужен заголовок Authorization: Bearer с MIDDLEWARE_ADMIN_TOKEN",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Загрузить курсы валют",
+                "parameters": [
+                    {
+                        "description": "Курсы",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.RatesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.RatesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rates/import": {
+            "post": {
+                "description": "Тело - строки \"currency,rate\", первая строка может быть заголовком. Базовая валюта - в параметре base. Нужен заголовок Authorization: Bearer с MIDDLEWARE_ADMIN_TOKEN",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Импорт курсов из CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "RUB",
+                        "description": "Базовая валюта",
+                        "name": "base",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "CSV с курсами",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.RatesResponse"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/resttransport.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rates/{id}": {
+            "get": {
+                "description": "Возвращает таблицу курсов, на которую ссылается rate_snapshot_id вычисления",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Таблица курсов по идентификатору",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Индентификатор",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/resttransport.RatesResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -254,23 +603,210 @@ const docTemplate = `{
                 "expression": {
                     "type": "string",
                     "example": "2+3/2"
+                },
+                "format": {
+                    "$ref": "#/definitions/resttransport.ResultFormat"
+                },
+                "int_type": {
+                    "type": "string",
+                    "enum": [
+                        "int8",
+                        "int16",
+                        "int32",
+                        "int64",
+                        "uint8",
+                        "uint16",
+                        "uint32",
+                        "uint64"
+                    ],
+                    "example": "int64"
+                },
+                "locale": {
+                    "description": "запись чисел в выражении, без неё - без локали",
+                    "type": "string",
+                    "example": "ru"
+                },
+                "output": {
+                    "description": "fraction без precision - exact",
+                    "type": "string",
+                    "enum": [
+                        "decimal",
+                        "fraction"
+                    ],
+                    "example": "fraction"
+                },
+                "overflow": {
+                    "type": "string",
+                    "enum": [
+                        "wrap",
+                        "saturate",
+                        "error"
+                    ],
+                    "example": "wrap"
+                },
+                "precision": {
+                    "type": "string",
+                    "enum": [
+                        "float",
+                        "exact",
+                        "integer",
+                        "complex"
+                    ],
+                    "example": "exact"
+                },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "half-even",
+                        "half-up",
+                        "half-down",
+                        "up",
+                        "truncate",
+                        "ceiling",
+                        "floor"
+                    ],
+                    "example": "half-even"
+                },
+                "scale": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "units": {
+                    "description": "единицы измерения: \"5 km + 300 m in mi\"",
+                    "type": "boolean",
+                    "example": true
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
                 }
             }
         },
         "resttransport.CalcResponse": {
             "type": "object",
             "properties": {
+                "binary": {
+                    "type": "string",
+                    "example": "0b11111111"
+                },
+                "canonical_expression": {
+                    "type": "string",
+                    "example": "2+3/2"
+                },
+                "complex": {
+                    "$ref": "#/definitions/resttransport.ComplexResponse"
+                },
                 "expression": {
                     "type": "string",
                     "example": "2+3/2"
                 },
+                "format": {
+                    "$ref": "#/definitions/resttransport.ResultFormat"
+                },
+                "formatted": {
+                    "type": "string",
+                    "example": "3.50"
+                },
+                "fraction": {
+                    "$ref": "#/definitions/resttransport.FractionResponse"
+                },
+                "hex": {
+                    "type": "string",
+                    "example": "0xff"
+                },
                 "id": {
                     "type": "string",
                     "example": "a8098c1a-f86e-11da-bd1a-00112444be1e"
                 },
+                "int_type": {
+                    "type": "string",
+                    "example": "int64"
+                },
+                "locale": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "normalized_expression": {
+                    "type": "string",
+                    "example": "2+3/2"
+                },
+                "output": {
+                    "type": "string",
+                    "example": "fraction"
+                },
+                "overflow": {
+                    "type": "string",
+                    "example": "wrap"
+                },
+                "precision": {
+                    "type": "string",
+                    "example": "exact"
+                },
+                "rate_snapshot_id": {
+                    "type": "string",
+                    "example": "0b7c2c9e-5f0e-4a53-9d43-0f1d1a8b6c11"
+                },
                 "result": {
                     "type": "string",
                     "example": "3.5"
+                },
+                "result_type": {
+                    "type": "string",
+                    "enum": [
+                        "number",
+                        "boolean"
+                    ],
+                    "example": "number"
+                },
+                "rounding": {
+                    "type": "string",
+                    "example": "half-even"
+                },
+                "scale": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "km"
+                },
+                "units": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "value": {
+                    "description": "result в типе result_type: 3.5 или true; дробь и комплексное число - строкой"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                }
+            }
+        },
+        "resttransport.ComplexResponse": {
+            "type": "object",
+            "properties": {
+                "abs": {
+                    "type": "number",
+                    "example": 11.180339887498949
+                },
+                "arg": {
+                    "type": "number",
+                    "example": -0.17985349979247828
+                },
+                "im": {
+                    "type": "number",
+                    "example": -2
+                },
+                "re": {
+                    "type": "number",
+                    "example": 11
                 }
             }
         },
@@ -282,6 +818,295 @@ const docTemplate = `{
                     "example": "\u003ccause\u003e"
                 }
             }
+        },
+        "resttransport.ExplainResponse": {
+            "type": "object",
+            "properties": {
+                "binary": {
+                    "type": "string",
+                    "example": "0b11111111"
+                },
+                "canonical_expression": {
+                    "type": "string",
+                    "example": "2+3/2"
+                },
+                "complex": {
+                    "$ref": "#/definitions/resttransport.ComplexResponse"
+                },
+                "expression": {
+                    "type": "string",
+                    "example": "2+3/2"
+                },
+                "format": {
+                    "$ref": "#/definitions/resttransport.ResultFormat"
+                },
+                "formatted": {
+                    "type": "string",
+                    "example": "3.50"
+                },
+                "fraction": {
+                    "$ref": "#/definitions/resttransport.FractionResponse"
+                },
+                "hex": {
+                    "type": "string",
+                    "example": "0xff"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "a8098c1a-f86e-11da-bd1a-00112444be1e"
+                },
+                "int_type": {
+                    "type": "string",
+                    "example": "int64"
+                },
+                "locale": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "normalized_expression": {
+                    "type": "string",
+                    "example": "2+3/2"
+                },
+                "output": {
+                    "type": "string",
+                    "example": "fraction"
+                },
+                "overflow": {
+                    "type": "string",
+                    "example": "wrap"
+                },
+                "precision": {
+                    "type": "string",
+                    "example": "exact"
+                },
+                "rate_snapshot_id": {
+                    "type": "string",
+                    "example": "0b7c2c9e-5f0e-4a53-9d43-0f1d1a8b6c11"
+                },
+                "result": {
+                    "type": "string",
+                    "example": "3.5"
+                },
+                "result_type": {
+                    "type": "string",
+                    "enum": [
+                        "number",
+                        "boolean"
+                    ],
+                    "example": "number"
+                },
+                "rounding": {
+                    "type": "string",
+                    "example": "half-even"
+                },
+                "scale": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/resttransport.StepResponse"
+                    }
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "km"
+                },
+                "units": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "value": {
+                    "description": "result в типе result_type: 3.5 или true; дробь и комплексное число - строкой"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                }
+            }
+        },
+        "resttransport.FractionResponse": {
+            "type": "object",
+            "properties": {
+                "decimal": {
+                    "type": "string",
+                    "example": "3.5"
+                },
+                "denominator": {
+                    "type": "string",
+                    "example": "2"
+                },
+                "mixed": {
+                    "type": "string",
+                    "example": "3 1/2"
+                },
+                "numerator": {
+                    "type": "string",
+                    "example": "7"
+                }
+            }
+        },
+        "resttransport.ParseErrorResponse": {
+            "type": "object",
+            "properties": {
+                "byte_offset": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "caret": {
+                    "type": "string",
+                    "example": "1 + * 2\n    ^"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "invalid format: consecutive operators at offset 4"
+                },
+                "expected": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "number",
+                        "identifier",
+                        "(",
+                        "+",
+                        "-"
+                    ]
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "token": {
+                    "type": "string",
+                    "example": "*"
+                }
+            }
+        },
+        "resttransport.RatesRequest": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "rates": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    },
+                    "example": {
+                        "EUR": 100.1,
+                        "USD": 92.5
+                    }
+                }
+            }
+        },
+        "resttransport.RatesResponse": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2026-10-18T18:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0b7c2c9e-5f0e-4a53-9d43-0f1d1a8b6c11"
+                },
+                "rates": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    },
+                    "example": {
+                        "EUR": 100.1,
+                        "RUB": 1,
+                        "USD": 92.5
+                    }
+                }
+            }
+        },
+        "resttransport.ResultFormat": {
+            "type": "object",
+            "properties": {
+                "decimals": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "digits": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "grouping": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "notation": {
+                    "type": "string",
+                    "enum": [
+                        "plain",
+                        "scientific",
+                        "engineering"
+                    ],
+                    "example": "plain"
+                },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "half-even",
+                        "half-up",
+                        "half-down",
+                        "up",
+                        "truncate",
+                        "ceiling",
+                        "floor"
+                    ],
+                    "example": "half-up"
+                }
+            }
+        },
+        "resttransport.StepResponse": {
+            "type": "object",
+            "properties": {
+                "expression": {
+                    "type": "string",
+                    "example": "(1+2)*3"
+                },
+                "interpretation": {
+                    "description": "Interpretation - только у процентных операций: \"200 + 10%\" - \"200 + 200 * 10 / 100\"",
+                    "type": "string",
+                    "example": "200 + 200 * 10 / 100"
+                },
+                "operands": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "3",
+                        "3"
+                    ]
+                },
+                "operator": {
+                    "type": "string",
+                    "example": "*"
+                },
+                "result": {
+                    "type": "string",
+                    "example": "9"
+                }
+            }
         }
     }
 }`
@@ -18,4 +18,5 @@ func RegisterRoutes(e *echo.Echo, t Transport, m middlewares.Middleware) {
 
 	// group := e.Group("/v1", m.AuthToken)
 	apirest.RegisterCalculation(e, t, m)
+	apirest.RegisterRates(e, t, m)
 }
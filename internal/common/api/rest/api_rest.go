@@ -11,6 +11,12 @@ type Transport interface {
 	PostCalculation(c echo.Context) error
 	DeleteCalcById(c echo.Context) error
 	PatchCalculationById(c echo.Context) error
+	GetExplainById(c echo.Context) error
+	PostExplain(c echo.Context) error
+	GetLatestRates(c echo.Context) error
+	GetRatesById(c echo.Context) error
+	PutRates(c echo.Context) error
+	PostRatesCSV(c echo.Context) error
 }
 
 func RegisterCalculation(e *echo.Echo, t Transport, m middlewares.Middleware) {
@@ -21,4 +27,16 @@ func RegisterCalculation(e *echo.Echo, t Transport, m middlewares.Middleware) {
 	group.POST("", t.PostCalculation)
 	group.DELETE("/:id", t.DeleteCalcById)
 	group.PATCH("/:id", t.PatchCalculationById)
+	group.GET("/:id/explain", t.GetExplainById)
+	group.POST("/explain", t.PostExplain)
+}
+
+// RegisterRates - курсы читают все, загружают только администраторы
+func RegisterRates(e *echo.Echo, t Transport, m middlewares.Middleware) {
+	group := e.Group("/rates")
+
+	group.GET("", t.GetLatestRates)
+	group.GET("/:id", t.GetRatesById)
+	group.PUT("", t.PutRates, m.AdminToken)
+	group.POST("/import", t.PostRatesCSV, m.AdminToken)
 }
@@ -7,6 +7,7 @@ type Config struct {
 	Server      Server      `envconfig:"SERVER" required:"true"`
 	Logger      Logger      `envconfig:"LOGGER" required:"true"`
 	Middlerware Middlerware `envconfig:"MIDDLEWARE" required:"true"`
+	Calculator  Calculator  `envconfig:"CALC"`
 }
 
 func init() {
@@ -45,5 +46,16 @@ type Logger struct {
 }
 
 type Middlerware struct {
-	AuthToken string `envconfig:"AUTH_TOKEN"`
+	AuthToken  string `envconfig:"AUTH_TOKEN"`
+	AdminToken string `envconfig:"ADMIN_TOKEN"` // пустой - административные ручки закрыты
+}
+
+// Calculator - ограничения на вычисление одного выражения, 0 - без ограничения.
+// MaxDepth 0 - глубина по умолчанию калькулятора: без ограничения глубокая вложенность переполнит стек.
+type Calculator struct {
+	Timeout       time.Duration `envconfig:"TIMEOUT" default:"2s"`
+	MaxLength     int           `envconfig:"MAX_LENGTH" default:"10000"`
+	MaxTokens     int           `envconfig:"MAX_TOKENS" default:"5000"`
+	MaxDepth      int           `envconfig:"MAX_DEPTH" default:"200"`
+	MaxOperations int           `envconfig:"MAX_OPERATIONS" default:"2000"`
 }
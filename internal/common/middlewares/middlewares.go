@@ -1,12 +1,17 @@
 package middlewares
 
 import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
 	"github.com/eragon-mdi/calc-back/internal/common/configs"
 	"github.com/labstack/echo/v4"
 )
 
 type Middleware interface {
 	AuthToken(echo.HandlerFunc) echo.HandlerFunc
+	AdminToken(echo.HandlerFunc) echo.HandlerFunc
 }
 
 type customMiddleware struct {
@@ -31,3 +36,16 @@ func (m customMiddleware) AuthToken(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// AdminToken - административные ручки по заголовку "Authorization: Bearer <MIDDLEWARE_ADMIN_TOKEN>";
+// без настроенного токена они закрыты
+func (m customMiddleware) AdminToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || m.cfg.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.AdminToken)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+
+		return next(c)
+	}
+}
@@ -1,9 +1,19 @@
 package domain
 
 type Calculation struct {
-	ID         string
-	Expression string
-	Result     string
+	ID                   string
+	Expression           string // как ввёл пользователь, в записи Locale
+	NormalizedExpression string // Expression со знаками из документов, заменёнными на операторы: × - *, √x - sqrt(x)
+	CanonicalExpression  string // NormalizedExpression без локали: десятичная точка, аргументы через запятую
+	Locale               string
+	Variables            map[string]float64
+	Precision            Precision
+	Format               Format
+	Result               string // машинное значение, Format и Locale применяются при выводе
+	ResultType           string // number или boolean: у сравнений и логических операций Result - true или false
+	Units                bool   // единицы измерения в выражении: 5 km + 300 m in mi
+	Unit                 string // единица Result в режиме Units, пустая у безразмерного результата
+	RateSnapshotID       string // таблица курсов, по которой посчитаны валюты; пусто, если валют нет
 }
 
 type CalcID struct {
@@ -11,5 +21,47 @@ type CalcID struct {
 }
 
 type CalcExpr struct {
-	Expr string
+	Expr      string
+	Locale    string
+	Variables map[string]float64
+	Precision Precision
+	Format    Format
+	Units     bool
+}
+
+// Precision - режим вычисления; Output, Scale и Rounding задают вывод точного (exact) результата,
+// IntType и Overflow - тип и поведение при переполнении целочисленного (integer)
+type Precision struct {
+	Mode     string
+	Output   string // decimal или fraction: 0.5 или 1/2
+	Scale    *int
+	Rounding string
+	IntType  string
+	Overflow string
+}
+
+// Format - вид результата для человека, пустой Format - без форматирования.
+// Digits (значащие цифры) и Decimals (знаки после запятой) взаимоисключающие.
+type Format struct {
+	Digits   *int
+	Decimals *int
+	Rounding string
+	Notation string
+	Grouping bool
+}
+
+// Explanation - вычисление и его шаги в порядке выполнения
+type Explanation struct {
+	Calculation Calculation
+	Steps       []Step
+}
+
+// Step - действие над вычисленными операндами; Expression - подвыражение из исходной записи,
+// Interpretation - как понята процентная операция
+type Step struct {
+	Expression     string
+	Operator       string
+	Operands       []string
+	Result         string
+	Interpretation string
 }
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// RateSnapshot - таблица курсов валют, загруженная администратором. После загрузки не меняется,
+// вычисление ссылается на неё, чтобы результат можно было повторить после смены курсов.
+type RateSnapshot struct {
+	ID        string
+	Base      string             // базовая валюта, её курс 1
+	Rates     map[string]float64 // стоимость единицы валюты в Base
+	CreatedAt time.Time
+}
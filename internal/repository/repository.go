@@ -9,3 +9,7 @@ import (
 func New(s storage.Storage) service.Repository {
 	return sqlrepo.New(s.SQL())
 }
+
+func NewRates(s storage.Storage) service.RateRepository {
+	return sqlrepo.NewRates(s.SQL())
+}
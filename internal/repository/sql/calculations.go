@@ -15,6 +15,7 @@ const (
 	ErrFailedStartTX      = "repo: failed to start tx"
 	ErrFailedCommitTX     = "repo: failed to commit tx"
 	ErrFailedRollbackTX   = "repo: failed rollback tx"
+	ErrUnexpectedJSONType = "repo: unexpected type for json column"
 )
 
 func (r sqlRepo) GetCalculations(maxCount int) (calcs []domain.Calculation, err error) {
@@ -25,8 +26,8 @@ func (r sqlRepo) GetCalculations(maxCount int) (calcs []domain.Calculation, err
 	defer rows.Close()
 
 	for rows.Next() {
-		calc := domain.Calculation{}
-		if err := rows.Scan(&calc.ID, &calc.Expression, &calc.Result); err != nil {
+		calc, err := scanCalc(rows)
+		if err != nil {
 			return nil, errors.Wrap(err, ErrFailedScan)
 		}
 
@@ -40,11 +41,10 @@ func (r sqlRepo) GetCalculations(maxCount int) (calcs []domain.Calculation, err
 }
 
 func (r sqlRepo) GetCalculation(id string) (domain.Calculation, error) {
-	var calc = domain.Calculation{}
-
 	row := r.s.QueryRow(getCalcById, id)
 
-	if err := row.Scan(&calc.ID, &calc.Expression, &calc.Result); err != nil {
+	calc, err := scanCalc(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Calculation{}, domain.ErrNotFound
 		}
@@ -73,11 +73,10 @@ func (r sqlRepo) DeleteCalculation(id string) error {
 }
 
 func (r sqlRepo) SaveTask(calc domain.Calculation) (domain.Calculation, error) {
-	var newCalc = domain.Calculation{}
+	row := r.s.QueryRow(insertCalc, calcArgs(&calc)...)
 
-	row := r.s.QueryRow(insertCalc, calc.ID, calc.Expression, calc.Result)
-
-	if err := row.Scan(&newCalc.ID, &newCalc.Expression, &newCalc.Result); err != nil {
+	newCalc, err := scanCalc(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Calculation{}, domain.ErrNotFound
 		}
@@ -88,11 +87,10 @@ func (r sqlRepo) SaveTask(calc domain.Calculation) (domain.Calculation, error) {
 }
 
 func (r sqlRepo) UpdateTaskInfo(calc domain.Calculation) (domain.Calculation, error) {
-	var updatedCalc = domain.Calculation{}
+	row := r.s.QueryRow(updateCalc, calcArgs(&calc)...)
 
-	row := r.s.QueryRow(updateCalc, calc.ID, calc.Expression, calc.Result)
-
-	if err := row.Scan(&updatedCalc.ID, &updatedCalc.Expression, &updatedCalc.Result); err != nil {
+	updatedCalc, err := scanCalc(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Calculation{}, domain.ErrNotFound
 		}
@@ -101,3 +99,69 @@ func (r sqlRepo) UpdateTaskInfo(calc domain.Calculation) (domain.Calculation, er
 
 	return updatedCalc, nil
 }
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCalc(s scanner) (domain.Calculation, error) {
+	var (
+		calc         domain.Calculation
+		rateSnapshot sql.NullString
+	)
+
+	err := s.Scan(
+		&calc.ID,
+		&calc.Expression,
+		&calc.NormalizedExpression,
+		&calc.CanonicalExpression,
+		&calc.Locale,
+		jsonColumn(&calc.Variables),
+		&calc.Precision.Mode,
+		&calc.Precision.Output,
+		&calc.Precision.Scale,
+		&calc.Precision.Rounding,
+		&calc.Precision.IntType,
+		&calc.Precision.Overflow,
+		&calc.Format.Digits,
+		&calc.Format.Decimals,
+		&calc.Format.Rounding,
+		&calc.Format.Notation,
+		&calc.Format.Grouping,
+		&calc.Result,
+		&calc.ResultType,
+		&calc.Units,
+		&calc.Unit,
+		&rateSnapshot,
+	)
+	calc.RateSnapshotID = rateSnapshot.String
+
+	return calc, err
+}
+
+func calcArgs(calc *domain.Calculation) []any {
+	return []any{
+		calc.ID,
+		calc.Expression,
+		calc.NormalizedExpression,
+		calc.CanonicalExpression,
+		calc.Locale,
+		jsonColumn(&calc.Variables),
+		calc.Precision.Mode,
+		calc.Precision.Output,
+		calc.Precision.Scale,
+		calc.Precision.Rounding,
+		calc.Precision.IntType,
+		calc.Precision.Overflow,
+		calc.Format.Digits,
+		calc.Format.Decimals,
+		calc.Format.Rounding,
+		calc.Format.Notation,
+		calc.Format.Grouping,
+		calc.Result,
+		calc.ResultType,
+		calc.Units,
+		calc.Unit,
+		sql.NullString{String: calc.RateSnapshotID, Valid: calc.RateSnapshotID != ""},
+	}
+}
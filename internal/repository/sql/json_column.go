@@ -0,0 +1,41 @@
+package sqlrepo
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/go-faster/errors"
+)
+
+// jsonColumnValue - хранение значения в JSONB колонке через sql.Scanner и driver.Valuer
+type jsonColumnValue[T any] struct {
+	v *T
+}
+
+func jsonColumn[T any](v *T) jsonColumnValue[T] {
+	return jsonColumnValue[T]{v: v}
+}
+
+func (c jsonColumnValue[T]) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return errors.Errorf("%s: %T", ErrUnexpectedJSONType, src)
+	}
+
+	return json.Unmarshal(data, c.v)
+}
+
+func (c jsonColumnValue[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(c.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
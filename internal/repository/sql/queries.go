@@ -1,8 +1,12 @@
 package sqlrepo
 
+// порядок колонок совпадает с scanCalc и calcArgs
+const calcColumns = `id, expression, normalized_expression, canonical_expression, locale, variables, precision_mode, precision_output, precision_scale, precision_rounding, precision_int_type, precision_overflow, ` +
+	`format_digits, format_decimals, format_rounding, format_notation, format_grouping, result, result_type, units, unit, rate_snapshot_id`
+
 const getCalcsWithMax = `
 SELECT 
-	id, expression, result
+	` + calcColumns + `
 FROM
 	calculations
 ORDER BY id DESC
@@ -11,7 +15,7 @@ LIMIT $1
 
 const getCalcById = `
 SELECT 
-	id, expression, result
+	` + calcColumns + `
 FROM
 	calculations
 WHERE id = $1
@@ -27,19 +31,64 @@ WHERE id = $1
 const insertCalc = `
 INSERT INTO 
 	calculations
-	(id, expression, result)
+	(` + calcColumns + `)
 VALUES
-	($1, $2, $3)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 RETURNING
-	id, expression, result
+	` + calcColumns + `
 `
 
 const updateCalc = `
 UPDATE
 	calculations
 SET
-	expression = $2, result = $3
+	expression = $2, normalized_expression = $3, canonical_expression = $4, locale = $5, variables = $6,
+	precision_mode = $7, precision_output = $8, precision_scale = $9, precision_rounding = $10,
+	precision_int_type = $11, precision_overflow = $12,
+	format_digits = $13, format_decimals = $14, format_rounding = $15, format_notation = $16, format_grouping = $17,
+	result = $18, result_type = $19, units = $20, unit = $21, rate_snapshot_id = $22
 WHERE
 	id = $1
-RETURNING id, expression, result
+RETURNING ` + calcColumns + `
+`
+
+const insertRateSnapshot = `
+INSERT INTO
+	rate_snapshots
+	(id, base, created_at)
+VALUES
+	($1, $2, $3)
+`
+
+const insertCurrencyRate = `
+INSERT INTO
+	currency_rates
+	(snapshot_id, currency, rate)
+VALUES
+	($1, $2, $3)
+`
+
+const getLatestRateSnapshot = `
+SELECT
+	id, base, created_at
+FROM
+	rate_snapshots
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+const getRateSnapshotById = `
+SELECT
+	id, base, created_at
+FROM
+	rate_snapshots
+WHERE id = $1
+`
+
+const getCurrencyRates = `
+SELECT
+	currency, rate
+FROM
+	currency_rates
+WHERE snapshot_id = $1
 `
@@ -0,0 +1,81 @@
+package sqlrepo
+
+import (
+	"database/sql"
+
+	"github.com/eragon-mdi/calc-back/internal/domain"
+	"github.com/go-faster/errors"
+)
+
+// SaveRateSnapshot - таблица курсов и её строки в одной транзакции
+func (r sqlRepo) SaveRateSnapshot(snapshot domain.RateSnapshot) (_ domain.RateSnapshot, err error) {
+	tx, err := r.s.Begin()
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedStartTX)
+	}
+	// ошибка отката не заменяет причину, из-за которой транзакция откатывается
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			err = errors.Join(err, errors.Wrap(rbErr, ErrFailedRollbackTX))
+		}
+	}()
+
+	if _, err = tx.Exec(insertRateSnapshot, snapshot.ID, snapshot.Base, snapshot.CreatedAt); err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedExec)
+	}
+	for currency, rate := range snapshot.Rates {
+		if _, err = tx.Exec(insertCurrencyRate, snapshot.ID, currency, rate); err != nil {
+			return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedExec)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedCommitTX)
+	}
+
+	return snapshot, nil
+}
+
+func (r sqlRepo) GetLatestRateSnapshot() (domain.RateSnapshot, error) {
+	return r.getRateSnapshot(r.s.QueryRow(getLatestRateSnapshot))
+}
+
+func (r sqlRepo) GetRateSnapshot(id string) (domain.RateSnapshot, error) {
+	return r.getRateSnapshot(r.s.QueryRow(getRateSnapshotById, id))
+}
+
+func (r sqlRepo) getRateSnapshot(row scanner) (domain.RateSnapshot, error) {
+	var snapshot domain.RateSnapshot
+	if err := row.Scan(&snapshot.ID, &snapshot.Base, &snapshot.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.RateSnapshot{}, domain.ErrNotFound
+		}
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedScan)
+	}
+
+	rows, err := r.s.Query(getCurrencyRates, snapshot.ID)
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedQuery)
+	}
+	defer rows.Close()
+
+	snapshot.Rates = make(map[string]float64)
+	for rows.Next() {
+		var (
+			currency string
+			rate     float64
+		)
+		if err := rows.Scan(&currency, &rate); err != nil {
+			return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedScan)
+		}
+		snapshot.Rates[currency] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, ErrFailedQuery)
+	}
+
+	return snapshot, nil
+}
@@ -14,3 +14,9 @@ func New(s sqlstore.Storage) service.Repository {
 		s: s,
 	}
 }
+
+func NewRates(s sqlstore.Storage) service.RateRepository {
+	return &sqlRepo{
+		s: s,
+	}
+}
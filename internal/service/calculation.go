@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/eragon-mdi/calc-back/internal/domain"
 	"github.com/go-faster/errors"
 	"github.com/google/uuid"
@@ -39,10 +41,22 @@ func (s service) GetCalculationById(id domain.CalcID) (domain.Calculation, error
 	return task, nil
 }
 
-func (s service) CreateCalculation(expr domain.CalcExpr) (domain.Calculation, error) {
-	calc, err := calculate(domain.Calculation{Expression: expr.Expr})
+func (s service) CreateCalculation(ctx context.Context, expr domain.CalcExpr) (domain.Calculation, error) {
+	rates, err := s.latestRates(expr.Expr)
+	if err != nil {
+		return domain.Calculation{}, err
+	}
+
+	calc, err := s.calculate(ctx, domain.Calculation{
+		Expression: expr.Expr,
+		Locale:     expr.Locale,
+		Variables:  expr.Variables,
+		Precision:  expr.Precision,
+		Format:     expr.Format,
+		Units:      expr.Units,
+	}, rates)
 	if err != nil {
-		return domain.Calculation{}, domain.ErrValidation
+		return domain.Calculation{}, validationErr(err)
 	}
 
 	calc.ID = uuid.NewString()
@@ -55,6 +69,47 @@ func (s service) CreateCalculation(expr domain.CalcExpr) (domain.Calculation, er
 	return calc, nil
 }
 
+func (s service) ExplainCalculationById(ctx context.Context, id domain.CalcID) (domain.Explanation, error) {
+	calc, err := s.r.GetCalculation(id.ID)
+	if err != nil {
+		return domain.Explanation{}, errors.Wrap(err, "service: failed to get calc")
+	}
+
+	rates, err := s.storedRates(calc)
+	if err != nil {
+		return domain.Explanation{}, err
+	}
+
+	explanation, err := s.explain(ctx, calc, rates)
+	if err != nil {
+		return domain.Explanation{}, validationErr(err)
+	}
+
+	return explanation, nil
+}
+
+// ExplainExpression - шаги вычисления без сохранения
+func (s service) ExplainExpression(ctx context.Context, expr domain.CalcExpr) (domain.Explanation, error) {
+	rates, err := s.latestRates(expr.Expr)
+	if err != nil {
+		return domain.Explanation{}, err
+	}
+
+	explanation, err := s.explain(ctx, domain.Calculation{
+		Expression: expr.Expr,
+		Locale:     expr.Locale,
+		Variables:  expr.Variables,
+		Precision:  expr.Precision,
+		Format:     expr.Format,
+		Units:      expr.Units,
+	}, rates)
+	if err != nil {
+		return domain.Explanation{}, validationErr(err)
+	}
+
+	return explanation, nil
+}
+
 func (s service) DeleteCalcById(id domain.CalcID) error {
 	if err := s.r.DeleteCalculation(id.ID); err != nil {
 		return errors.Wrap(err, "service: failed to delete calc")
@@ -63,10 +118,16 @@ func (s service) DeleteCalcById(id domain.CalcID) error {
 	return nil
 }
 
-func (s service) UpdateCalculationById(calc domain.Calculation) (domain.Calculation, error) {
-	calc, err := calculate(calc)
+// UpdateCalculationById - новое выражение считается по последним курсам
+func (s service) UpdateCalculationById(ctx context.Context, calc domain.Calculation) (domain.Calculation, error) {
+	rates, err := s.latestRates(calc.Expression)
+	if err != nil {
+		return domain.Calculation{}, err
+	}
+
+	calc, err = s.calculate(ctx, calc, rates)
 	if err != nil {
-		return domain.Calculation{}, domain.ErrValidation
+		return domain.Calculation{}, validationErr(err)
 	}
 
 	newCalc, err := s.r.UpdateTaskInfo(calc)
@@ -1,12 +1,16 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/eragon-mdi/calc-back/internal/common/configs"
 	"github.com/eragon-mdi/calc-back/internal/domain"
 	"github.com/eragon-mdi/calc-back/internal/service/mocks"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -141,16 +145,92 @@ func Test_service_GetCalculationById(t *testing.T) {
 
 func Test_service_CreateCalculation(t *testing.T) {
 	exprValid := domain.CalcExpr{Expr: "1+2"}
-	exprInvalid := domain.CalcExpr{Expr: "1++2"}
+	exprInvalid := domain.CalcExpr{Expr: "1+*2"}
+	exprGrouped := domain.CalcExpr{Expr: "(1+2)*(3-1)"}
+	exprExact := domain.CalcExpr{Expr: "0.1+0.2", Precision: domain.Precision{Mode: "exact"}}
+	exprInteger := domain.CalcExpr{Expr: "0x7F + 1", Precision: domain.Precision{Mode: "integer", IntType: "int8"}}
+	exprComplex := domain.CalcExpr{Expr: "(3+4i) * (1-2i)", Precision: domain.Precision{Mode: "complex"}}
+	exprUnits := domain.CalcExpr{Expr: "5 km + 300 m", Units: true}
+	exprWithVars := domain.CalcExpr{Expr: "x*y+1", Variables: map[string]float64{"x": 2, "y": 3}}
+	exprCurrency := domain.CalcExpr{Expr: "100 USD in RUB"}
+	exprUndefinedVar := domain.CalcExpr{Expr: "x*y+1", Variables: map[string]float64{"x": 2}}
 
 	mockSavedCalc := domain.Calculation{
 		ID:         "uuid-generated", // этот ID вернём из моковой функции
 		Expression: "1+2",
 		Result:     "3",
 	}
+	mockSavedGroupCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "(1+2)*(3-1)",
+		Result:     "6",
+	}
+	mockSavedExactCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "0.1+0.2",
+		Precision:  domain.Precision{Mode: "exact", Output: "decimal", Rounding: "half-even"},
+		Result:     "0.3",
+	}
+	mockSavedIntegerCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "0x7F + 1",
+		Precision:  domain.Precision{Mode: "integer", IntType: "int8", Overflow: "wrap"},
+		Result:     "-128",
+	}
+	mockSavedComplexCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "(3+4i) * (1-2i)",
+		Precision:  domain.Precision{Mode: "complex"},
+		Result:     "11-2i",
+	}
+	mockSavedUnitsCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "5 km + 300 m",
+		Precision:  domain.Precision{Mode: "float"},
+		Result:     "5.3",
+		Units:      true,
+		Unit:       "km",
+	}
+	mockSavedCurrencyCalc := domain.Calculation{
+		ID:             "uuid-generated",
+		Expression:     "100 USD in RUB",
+		Precision:      domain.Precision{Mode: "float"},
+		Result:         "9000",
+		Unit:           "RUB",
+		RateSnapshotID: "rates-1",
+	}
+	mockRates := domain.RateSnapshot{ID: "rates-1", Base: "RUB", Rates: map[string]float64{"RUB": 1, "USD": 90}}
+	mockSavedBooleanCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "1 + 2 == 3 && !(2 < 1)",
+		Precision:  domain.Precision{Mode: "float"},
+		Result:     "true",
+		ResultType: "boolean",
+	}
+	mockSavedVarsCalc := domain.Calculation{
+		ID:         "uuid-generated",
+		Expression: "x*y+1",
+		Variables:  map[string]float64{"x": 2, "y": 3},
+		Result:     "7",
+	}
+
+	reg, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	double := calculable.Function{Name: "double", MinArgs: 1, MaxArgs: 1, Apply: func(args []float64) (float64, error) {
+		return args[0] * 2, nil
+	}}
+	if err := reg.RegisterFunction(double); err != nil {
+		t.Fatalf("RegisterFunction() error = %v", err)
+	}
+	two := 2
 
 	type fields struct {
-		r Repository
+		r     Repository
+		rates RateRepository
+		reg   *calculable.Registry
+		cfg   *configs.Calculator
 	}
 	type args struct {
 		expr domain.CalcExpr
@@ -177,6 +257,365 @@ func Test_service_CreateCalculation(t *testing.T) {
 			want:    mockSavedCalc,
 			wantErr: false,
 		},
+		{
+			name: "success with brackets",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Expression == "(1+2)*(3-1)" && calc.Result == "6"
+					})).Return(mockSavedGroupCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprGrouped},
+			want:    mockSavedGroupCalc,
+			wantErr: false,
+		},
+		{
+			name: "success in exact mode",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "0.3" && calc.Precision == mockSavedExactCalc.Precision
+					})).Return(mockSavedExactCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprExact},
+			want:    mockSavedExactCalc,
+			wantErr: false,
+		},
+		{
+			name: "success in integer mode wraps by default",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "-128" && calc.Precision == mockSavedIntegerCalc.Precision
+					})).Return(mockSavedIntegerCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprInteger},
+			want:    mockSavedIntegerCalc,
+			wantErr: false,
+		},
+		{
+			name: "success in complex mode",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "11-2i" && calc.Precision == mockSavedComplexCalc.Precision
+					})).Return(mockSavedComplexCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprComplex},
+			want:    mockSavedComplexCalc,
+			wantErr: false,
+		},
+		{
+			name: "fraction output in complex mode",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1", Precision: domain.Precision{Mode: "complex", Output: "fraction"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "success with units",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "5.3" && calc.Unit == "km" && calc.Units
+					})).Return(mockSavedUnitsCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprUnits},
+			want:    mockSavedUnitsCalc,
+			wantErr: false,
+		},
+		{
+			name: "units in exact mode",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "5 km", Units: true, Precision: domain.Precision{Mode: "exact"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "success with boolean result",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "true" && calc.ResultType == "boolean"
+					})).Return(mockSavedBooleanCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1 + 2 == 3 && !(2 < 1)"}},
+			want:    mockSavedBooleanCalc,
+			wantErr: false,
+		},
+		{
+			name: "boolean operand in arithmetic",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "(1 < 2) * 3"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "dimension mismatch",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "3 m + 2 s", Units: true}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "unknown integer type",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1", Precision: domain.Precision{Mode: "integer", IntType: "int128"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "success with variables",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Expression == "x*y+1" && calc.Result == "7" &&
+							reflect.DeepEqual(calc.Variables, exprWithVars.Variables)
+					})).Return(mockSavedVarsCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprWithVars},
+			want:    mockSavedVarsCalc,
+			wantErr: false,
+		},
+		{
+			name: "success with registry function",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Expression == "double(3) + 2" && calc.Result == "8"
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+				reg: reg,
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "double(3) + 2"}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "registry function without registry",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "double(1)"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "format is normalized and stored",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "333333.3333333333" && calc.Format.Notation == "plain" &&
+							calc.Format.Rounding == "half-even" && *calc.Format.Decimals == 2 && calc.Format.Grouping
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1e6/3", Format: domain.Format{Decimals: &two, Grouping: true}}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "localized expression is stored with canonical form",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "1004.75" && calc.Locale == "ru" &&
+							calc.Expression == "1 000 + 3,5 + 1,25" && calc.CanonicalExpression == "1_000 + 3.5 + 1.25"
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1 000 + 3,5 + 1,25", Locale: "ru-RU"}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "unicode symbols are normalized, original is kept",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "18" && calc.Expression == "√16 × 2² + 2" &&
+							calc.NormalizedExpression == "sqrt(16) * 2^2 + 2" && calc.CanonicalExpression == "sqrt(16) * 2^2 + 2"
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "√16 × 2² + 2"}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "fraction output implies exact mode",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "1/2" && calc.Precision.Mode == "exact" && calc.Precision.Output == "fraction"
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1/3 + 1/6", Precision: domain.Precision{Output: "fraction"}}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "fraction output ignores scale",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "-7/3" && *calc.Precision.Scale == 2
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "-2 - 1/3", Precision: domain.Precision{Mode: "exact", Output: "fraction", Scale: &two}}},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "fraction output in float mode",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1/3", Precision: domain.Precision{Mode: "float", Output: "fraction"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "unknown output",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1/3", Precision: domain.Precision{Output: "roman"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "unknown locale",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1", Locale: "tlh"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "conflicting format",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1", Format: domain.Format{Digits: &two, Decimals: &two}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "unknown notation",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1", Format: domain.Format{Notation: "roman"}}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "too complex expression is not saved",
+			fields: fields{
+				r:   mocks.NewRepository(t),
+				cfg: &configs.Calculator{Timeout: time.Second, MaxOperations: 2},
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1+2+3+4"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "currency conversion records rate snapshot",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "9000" && calc.Unit == "RUB" && calc.RateSnapshotID == "rates-1"
+					})).Return(mockSavedCurrencyCalc, nil).Once()
+					return m
+				}(),
+				rates: func() RateRepository {
+					m := mocks.NewRateRepository(t)
+					m.On("GetLatestRateSnapshot").Return(mockRates, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprCurrency},
+			want:    mockSavedCurrencyCalc,
+			wantErr: false,
+		},
+		{
+			name: "rates are not loaded without currencies",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("SaveTask", mock.MatchedBy(func(calc domain.Calculation) bool {
+						return calc.Result == "3" && calc.RateSnapshotID == ""
+					})).Return(mockSavedCalc, nil).Once()
+					return m
+				}(),
+				rates: mocks.NewRateRepository(t),
+			},
+			args:    args{expr: exprValid},
+			want:    mockSavedCalc,
+			wantErr: false,
+		},
+		{
+			name: "currency without loaded rates",
+			fields: fields{
+				r: mocks.NewRepository(t),
+				rates: func() RateRepository {
+					m := mocks.NewRateRepository(t)
+					m.On("GetLatestRateSnapshot").Return(domain.RateSnapshot{}, domain.ErrNotFound).Once()
+					return m
+				}(),
+			},
+			args:    args{expr: exprCurrency},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
 		{
 			name: "calculate returns validation error",
 			fields: fields{
@@ -186,6 +625,33 @@ func Test_service_CreateCalculation(t *testing.T) {
 			want:    domain.Calculation{},
 			wantErr: true,
 		},
+		{
+			name: "division by zero is not saved",
+			fields: fields{
+				r: mocks.NewRepository(t), // SaveTask не вызывается
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "1/(2-2)"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "overflow is not saved",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: domain.CalcExpr{Expr: "10^200*10^200"}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
+		{
+			name: "undefined variable",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args:    args{expr: exprUndefinedVar},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
 		{
 			name: "repository SaveTask error",
 			fields: fields{
@@ -205,9 +671,12 @@ func Test_service_CreateCalculation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := service{
-				r: tt.fields.r,
+				r:     tt.fields.r,
+				rates: tt.fields.rates,
+				reg:   tt.fields.reg,
+				cfg:   tt.fields.cfg,
 			}
-			got, err := s.CreateCalculation(tt.args.expr)
+			got, err := s.CreateCalculation(context.Background(), tt.args.expr)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("service.CreateCalculation() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -271,20 +740,60 @@ func Test_service_DeleteCalcById(t *testing.T) {
 
 func Test_service_UpdateCalculationById(t *testing.T) {
 	calcValid := domain.Calculation{
-		ID:         "1",
-		Expression: "1+2",
-		Result:     "3",
+		ID:                   "1",
+		Expression:           "1+2",
+		NormalizedExpression: "1+2",
+		CanonicalExpression:  "1+2",
+		Precision:            domain.Precision{Mode: "float"},
+		Result:               "3",
+		ResultType:           "number",
 	}
 
 	calcInvalid := domain.Calculation{
 		ID:         "1",
-		Expression: "1++2", // некорректно для calculate
+		Expression: "1+*2", // некорректно для calculate
 	}
 
 	mockUpdatedCalc := domain.Calculation{
-		ID:         "1",
-		Expression: "1+2",
-		Result:     "3",
+		ID:                   "1",
+		Expression:           "1+2",
+		NormalizedExpression: "1+2",
+		CanonicalExpression:  "1+2",
+		Precision:            domain.Precision{Mode: "float"},
+		Result:               "3",
+		ResultType:           "number",
+	}
+
+	calcPower := domain.Calculation{
+		ID:                   "1",
+		Expression:           "2^3%5+7//2",
+		NormalizedExpression: "2^3%5+7//2",
+		CanonicalExpression:  "2^3%5+7//2",
+		Precision:            domain.Precision{Mode: "float"},
+		Result:               "6",
+		ResultType:           "number",
+	}
+
+	scale := 2
+	calcExact := domain.Calculation{
+		ID:                   "1",
+		Expression:           "0.1+0.2+1/8",
+		NormalizedExpression: "0.1+0.2+1/8",
+		CanonicalExpression:  "0.1+0.2+1/8",
+		Precision:            domain.Precision{Mode: "exact", Output: "decimal", Scale: &scale, Rounding: "half-up"},
+		Result:               "0.43",
+		ResultType:           "number",
+	}
+
+	calcComparison := domain.Calculation{
+		ID:                   "1",
+		Expression:           "limit - used >= 10 && used > 0",
+		NormalizedExpression: "limit - used >= 10 && used > 0",
+		CanonicalExpression:  "limit - used >= 10 && used > 0",
+		Variables:            map[string]float64{"limit": 100, "used": 95},
+		Precision:            domain.Precision{Mode: "float"},
+		Result:               "false",
+		ResultType:           "boolean",
 	}
 
 	type fields struct {
@@ -313,6 +822,67 @@ func Test_service_UpdateCalculationById(t *testing.T) {
 			want:    mockUpdatedCalc,
 			wantErr: false,
 		},
+		{
+			name: "success with power, modulo and floor division",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("UpdateTaskInfo", calcPower).Return(calcPower, nil).Once()
+					return m
+				}(),
+			},
+			args:    args{calc: domain.Calculation{ID: "1", Expression: "2^3%5+7//2"}},
+			want:    calcPower,
+			wantErr: false,
+		},
+		{
+			name: "success in exact mode",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("UpdateTaskInfo", calcExact).Return(calcExact, nil).Once()
+					return m
+				}(),
+			},
+			args: args{calc: domain.Calculation{
+				ID:         "1",
+				Expression: "0.1+0.2+1/8",
+				Precision:  domain.Precision{Mode: "exact", Scale: &scale, Rounding: "half-up"},
+			}},
+			want:    calcExact,
+			wantErr: false,
+		},
+		{
+			name: "success with boolean result",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("UpdateTaskInfo", calcComparison).Return(calcComparison, nil).Once()
+					return m
+				}(),
+			},
+			args: args{calc: domain.Calculation{
+				ID:         "1",
+				Expression: "limit - used >= 10 && used > 0",
+				Variables:  map[string]float64{"limit": 100, "used": 95},
+				ResultType: "boolean", // тип прежнего результата пересчитывается
+			}},
+			want:    calcComparison,
+			wantErr: false,
+		},
+		{
+			name: "unknown rounding mode",
+			fields: fields{
+				r: mocks.NewRepository(t),
+			},
+			args: args{calc: domain.Calculation{
+				ID:         "1",
+				Expression: "1+2",
+				Precision:  domain.Precision{Mode: "exact", Rounding: "sideways"},
+			}},
+			want:    domain.Calculation{},
+			wantErr: true,
+		},
 		{
 			name: "calculate validation error",
 			fields: fields{
@@ -341,7 +911,7 @@ func Test_service_UpdateCalculationById(t *testing.T) {
 			s := service{
 				r: tt.fields.r,
 			}
-			got, err := s.UpdateCalculationById(tt.args.calc)
+			got, err := s.UpdateCalculationById(context.Background(), tt.args.calc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("service.UpdateCalculationById() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -352,3 +922,226 @@ func Test_service_UpdateCalculationById(t *testing.T) {
 		})
 	}
 }
+
+func Test_service_ExplainCalculationById(t *testing.T) {
+	stored := domain.Calculation{
+		ID:                   "1",
+		Expression:           "(x+2)*3",
+		NormalizedExpression: "(x+2)*3",
+		CanonicalExpression:  "(x+2)*3",
+		Variables:            map[string]float64{"x": 1},
+		Precision:            domain.Precision{Mode: "float"},
+		Result:               "9",
+		ResultType:           "number",
+	}
+
+	type fields struct {
+		r Repository
+	}
+	type args struct {
+		id domain.CalcID
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    domain.Explanation
+		wantErr bool
+	}{
+		{
+			name: "success",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("GetCalculation", "1").Return(stored, nil).Once()
+					return m
+				}(),
+			},
+			args: args{id: domain.CalcID{ID: "1"}},
+			want: domain.Explanation{
+				Calculation: stored,
+				Steps: []domain.Step{
+					{Expression: "x+2", Operator: "+", Operands: []string{"1", "2"}, Result: "3"},
+					{Expression: "(x+2)*3", Operator: "*", Operands: []string{"3", "3"}, Result: "9"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "repo error",
+			fields: fields{
+				r: func() Repository {
+					m := mocks.NewRepository(t)
+					m.On("GetCalculation", "1").Return(domain.Calculation{}, errors.New("not found")).Once()
+					return m
+				}(),
+			},
+			args:    args{id: domain.CalcID{ID: "1"}},
+			want:    domain.Explanation{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := service{
+				r: tt.fields.r,
+			}
+			got, err := s.ExplainCalculationById(context.Background(), tt.args.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("service.ExplainCalculationById() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("service.ExplainCalculationById() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_ExplainExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    domain.CalcExpr
+		want    domain.Explanation
+		wantErr error
+	}{
+		{
+			name: "exact mode",
+			expr: domain.CalcExpr{Expr: "0.1+0.2", Precision: domain.Precision{Mode: "exact"}},
+			want: domain.Explanation{
+				Calculation: domain.Calculation{
+					Expression:           "0.1+0.2",
+					NormalizedExpression: "0.1+0.2",
+					CanonicalExpression:  "0.1+0.2",
+					Precision:            domain.Precision{Mode: "exact", Output: "decimal", Rounding: "half-even"},
+					Result:               "0.3",
+					ResultType:           "number",
+				},
+				Steps: []domain.Step{{Expression: "0.1+0.2", Operator: "+", Operands: []string{"0.1", "0.2"}, Result: "0.3"}},
+			},
+		},
+		{
+			name: "percent interpretation",
+			expr: domain.CalcExpr{Expr: "200 + 10%"},
+			want: domain.Explanation{
+				Calculation: domain.Calculation{Expression: "200 + 10%", NormalizedExpression: "200 + 10%", CanonicalExpression: "200 + 10%", Precision: domain.Precision{Mode: "float"}, Result: "220", ResultType: "number"},
+				Steps: []domain.Step{{
+					Expression: "200 + 10%", Operator: "+%", Operands: []string{"200", "10"}, Result: "220", Interpretation: "200 + 200 * 10 / 100",
+				}},
+			},
+		},
+		{
+			name: "literal has no steps",
+			expr: domain.CalcExpr{Expr: "42"},
+			want: domain.Explanation{
+				Calculation: domain.Calculation{Expression: "42", NormalizedExpression: "42", CanonicalExpression: "42", Precision: domain.Precision{Mode: "float"}, Result: "42", ResultType: "number"},
+				Steps:       []domain.Step{},
+			},
+		},
+		{
+			name: "conditional",
+			expr: domain.CalcExpr{Expr: "2 > 1 ? 5 : 1/0"},
+			want: domain.Explanation{
+				Calculation: domain.Calculation{Expression: "2 > 1 ? 5 : 1/0", NormalizedExpression: "2 > 1 ? 5 : 1/0", CanonicalExpression: "2 > 1 ? 5 : 1/0", Precision: domain.Precision{Mode: "float"}, Result: "5", ResultType: "number"},
+				Steps: []domain.Step{
+					{Expression: "2 > 1", Operator: ">", Operands: []string{"2", "1"}, Result: "true"},
+					{Expression: "2 > 1 ? 5 : 1/0", Operator: "?", Operands: []string{"true", "5"}, Result: "5"},
+				},
+			},
+		},
+		{
+			name:    "math error",
+			expr:    domain.CalcExpr{Expr: "1/(2-2)"},
+			want:    domain.Explanation{},
+			wantErr: domain.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := service{
+				r: mocks.NewRepository(t), // ничего не сохраняется
+			}
+			got, err := s.ExplainExpression(context.Background(), tt.expr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("service.ExplainExpression() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("service.ExplainExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_LoadRates(t *testing.T) {
+	mockSaved := domain.RateSnapshot{ID: "rates-1", Base: "RUB", Rates: map[string]float64{"RUB": 1, "USD": 90}}
+
+	tests := []struct {
+		name     string
+		rates    RateRepository
+		snapshot domain.RateSnapshot
+		want     domain.RateSnapshot
+		wantErr  bool
+	}{
+		{
+			name: "base rate is added",
+			rates: func() RateRepository {
+				m := mocks.NewRateRepository(t)
+				m.On("SaveRateSnapshot", mock.MatchedBy(func(s domain.RateSnapshot) bool {
+					return s.ID != "" && !s.CreatedAt.IsZero() && reflect.DeepEqual(s.Rates, mockSaved.Rates)
+				})).Return(mockSaved, nil).Once()
+				return m
+			}(),
+			snapshot: domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"USD": 90}},
+			want:     mockSaved,
+			wantErr:  false,
+		},
+		{
+			name:     "invalid base",
+			rates:    mocks.NewRateRepository(t),
+			snapshot: domain.RateSnapshot{Base: "rub", Rates: map[string]float64{"USD": 90}},
+			want:     domain.RateSnapshot{},
+			wantErr:  true,
+		},
+		{
+			name:     "base rate other than 1",
+			rates:    mocks.NewRateRepository(t),
+			snapshot: domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"RUB": 2, "USD": 90}},
+			want:     domain.RateSnapshot{},
+			wantErr:  true,
+		},
+		{
+			name:     "negative rate",
+			rates:    mocks.NewRateRepository(t),
+			snapshot: domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"USD": -90}},
+			want:     domain.RateSnapshot{},
+			wantErr:  true,
+		},
+		{
+			name: "repository error",
+			rates: func() RateRepository {
+				m := mocks.NewRateRepository(t)
+				m.On("SaveRateSnapshot", mock.Anything).Return(domain.RateSnapshot{}, errors.New("db error")).Once()
+				return m
+			}(),
+			snapshot: domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"USD": 90}},
+			want:     domain.RateSnapshot{},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := service{
+				rates: tt.rates,
+			}
+			got, err := s.LoadRates(tt.snapshot)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("service.LoadRates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("service.LoadRates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/eragon-mdi/calc-back/internal/domain"
@@ -9,6 +12,7 @@ import (
 
 type calc struct {
 	domain.Calculation
+	currencies []string // валюты выражения: без них ссылка на курсы не сохраняется
 }
 
 func (c calc) GetExpression() string {
@@ -19,11 +23,231 @@ func (c *calc) SetResult(res float64) {
 	c.Result = strconv.FormatFloat(res, 'f', -1, 64)
 }
 
-func calculate(c domain.Calculation) (domain.Calculation, error) {
+func (c *calc) SetExactResult(res *big.Rat) {
+	if c.Precision.Output == string(calculable.OutputFraction) {
+		c.Result = calculable.FormatFraction(res)
+		return
+	}
+
+	scale := calculable.AutoScale
+	if c.Precision.Scale != nil {
+		scale = *c.Precision.Scale
+	}
+
+	c.Result = calculable.FormatDecimal(res, scale, calculable.RoundingMode(c.Precision.Rounding))
+}
+
+func (c *calc) SetIntegerResult(res *big.Int) {
+	c.Result = res.String()
+}
+
+func (c *calc) SetComplexResult(res complex128) {
+	c.Result = calculable.FormatComplex(res)
+}
+
+func (c *calc) SetBooleanResult(res bool) {
+	c.Result = strconv.FormatBool(res)
+	c.ResultType = string(calculable.ValueBoolean)
+}
+
+func (c *calc) SetQuantityResult(value float64, unit string) {
+	c.Result = strconv.FormatFloat(value, 'f', -1, 64)
+	c.Unit = unit
+}
+
+func (c *calc) SetCurrencies(codes []string) {
+	c.currencies = codes
+}
+
+func (c *calc) SetNormalizedExpression(expr string) {
+	c.NormalizedExpression = expr
+}
+
+func (c *calc) SetCanonicalExpression(expr string) {
+	c.CanonicalExpression = expr
+}
+
+func (s service) calculate(ctx context.Context, c domain.Calculation, rates domain.RateSnapshot) (domain.Calculation, error) {
+	return s.evaluate(ctx, c, rates, func(ctx context.Context, newC *calc, opts []calculable.Option) error {
+		return calculable.CalculateExpressionContext(ctx, newC, newC.Variables, opts...)
+	})
+}
+
+// explain - calculate с шагами вычисления
+func (s service) explain(ctx context.Context, c domain.Calculation, rates domain.RateSnapshot) (domain.Explanation, error) {
+	var steps []calculable.Step
+	result, err := s.evaluate(ctx, c, rates, func(ctx context.Context, newC *calc, opts []calculable.Option) (err error) {
+		steps, err = calculable.ExplainExpression(ctx, newC, newC.Variables, opts...)
+		return err
+	})
+	if err != nil {
+		return domain.Explanation{}, err
+	}
+
+	explanation := domain.Explanation{Calculation: result, Steps: make([]domain.Step, 0, len(steps))}
+	for _, step := range steps {
+		explanation.Steps = append(explanation.Steps, domain.Step(step))
+	}
+	return explanation, nil
+}
+
+// evaluate - общая часть calculate и explain: режим вычисления, локаль, курсы валют, реестр, ограничения и таймаут
+func (s service) evaluate(
+	ctx context.Context,
+	c domain.Calculation,
+	rates domain.RateSnapshot,
+	run func(ctx context.Context, newC *calc, opts []calculable.Option) error,
+) (domain.Calculation, error) {
+	precision, err := normalizePrecision(c.Precision)
+	if err != nil {
+		return domain.Calculation{}, err
+	}
+
+	format, err := normalizeFormat(c.Format)
+	if err != nil {
+		return domain.Calculation{}, err
+	}
+
+	locale, err := calculable.ParseLocale(c.Locale)
+	if err != nil {
+		return domain.Calculation{}, err
+	}
+
 	newC := calc{Calculation: c}
-	if err := calculable.CalculateExpression(&newC); err != nil {
+	newC.Precision = precision
+	newC.Format = format
+	newC.Locale = string(locale)
+	newC.Unit = ""
+	newC.ResultType = string(calculable.ValueNumber)
+	newC.RateSnapshotID = ""
+
+	opts := []calculable.Option{
+		calculable.WithPrecision(calculable.Precision(precision.Mode)),
+		calculable.WithIntegerType(calculable.IntType(precision.IntType)),
+		calculable.WithOverflow(calculable.Overflow(precision.Overflow)),
+		calculable.WithLocale(locale),
+		calculable.WithUnits(c.Units),
+		calculable.WithRegistry(s.reg),
+	}
+	// валюты считаются только во float, как и единицы измерения
+	if rates.ID != "" && precision.Mode == string(calculable.PrecisionFloat) {
+		opts = append(opts, calculable.WithRates(rates.Rates))
+		newC.RateSnapshotID = rates.ID
+	}
+	if s.cfg != nil {
+		opts = append(opts, calculable.WithLimits(calculable.Limits{
+			MaxLength:     s.cfg.MaxLength,
+			MaxTokens:     s.cfg.MaxTokens,
+			MaxDepth:      s.cfg.MaxDepth,
+			MaxOperations: s.cfg.MaxOperations,
+		}))
+
+		if s.cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+			defer cancel()
+		}
+	}
+	if err := run(ctx, &newC, opts); err != nil {
 		return domain.Calculation{}, err
 	}
+	if len(newC.currencies) == 0 {
+		newC.RateSnapshotID = ""
+	}
 
 	return newC.Calculation, nil
 }
+
+// normalizePrecision - проверяет режим и подставляет значения по умолчанию,
+// чтобы в хранилище попадал фактически использованный режим
+func normalizePrecision(p domain.Precision) (domain.Precision, error) {
+	output, err := calculable.ParseOutput(p.Output)
+	if err != nil {
+		return domain.Precision{}, err
+	}
+	// дробь получается только в точном режиме, поэтому он подразумевается
+	if p.Mode == "" && output == calculable.OutputFraction {
+		p.Mode = string(calculable.PrecisionExact)
+	}
+
+	mode, err := calculable.ParsePrecision(p.Mode)
+	if err != nil {
+		return domain.Precision{}, err
+	}
+	if output == calculable.OutputFraction && mode != calculable.PrecisionExact {
+		return domain.Precision{}, calculable.ErrFractionPrecision
+	}
+
+	switch mode {
+	case calculable.PrecisionExact:
+	case calculable.PrecisionInteger:
+		return normalizeInteger(p)
+	default:
+		return domain.Precision{Mode: string(mode)}, nil
+	}
+
+	rounding, err := calculable.ParseRoundingMode(p.Rounding)
+	if err != nil {
+		return domain.Precision{}, err
+	}
+	if p.Scale != nil && (*p.Scale < 0 || *p.Scale > calculable.MaxScale) {
+		return domain.Precision{}, calculable.ErrInvalidScale
+	}
+
+	return domain.Precision{Mode: string(mode), Output: string(output), Scale: p.Scale, Rounding: string(rounding)}, nil
+}
+
+func normalizeInteger(p domain.Precision) (domain.Precision, error) {
+	intType, err := calculable.ParseIntType(p.IntType)
+	if err != nil {
+		return domain.Precision{}, err
+	}
+	overflow, err := calculable.ParseOverflow(p.Overflow)
+	if err != nil {
+		return domain.Precision{}, err
+	}
+
+	return domain.Precision{
+		Mode:     string(calculable.PrecisionInteger),
+		IntType:  string(intType),
+		Overflow: string(overflow),
+	}, nil
+}
+
+// normalizeFormat - проверяет формат результата; пустой формат остаётся пустым
+func normalizeFormat(f domain.Format) (domain.Format, error) {
+	if f == (domain.Format{}) {
+		return f, nil
+	}
+
+	switch {
+	case f.Digits != nil && f.Decimals != nil:
+		return domain.Format{}, calculable.ErrFormatConflict
+	case f.Digits != nil && (*f.Digits < 1 || *f.Digits > calculable.MaxScale):
+		return domain.Format{}, calculable.ErrInvalidDigits
+	case f.Decimals != nil && (*f.Decimals < 0 || *f.Decimals > calculable.MaxScale):
+		return domain.Format{}, calculable.ErrInvalidScale
+	}
+
+	rounding, err := calculable.ParseRoundingMode(f.Rounding)
+	if err != nil {
+		return domain.Format{}, err
+	}
+	notation, err := calculable.ParseNotation(f.Notation)
+	if err != nil {
+		return domain.Format{}, err
+	}
+
+	return domain.Format{
+		Digits:   f.Digits,
+		Decimals: f.Decimals,
+		Rounding: string(rounding),
+		Notation: string(notation),
+		Grouping: f.Grouping,
+	}, nil
+}
+
+// validationErr - ошибка валидации с сохранением причины от вычислителя
+func validationErr(cause error) error {
+	return fmt.Errorf("%w: %w", domain.ErrValidation, cause)
+}
@@ -0,0 +1,202 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	domain "github.com/eragon-mdi/calc-back/internal/domain"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RateRepository is an autogenerated mock type for the RateRepository type
+type RateRepository struct {
+	mock.Mock
+}
+
+type RateRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *RateRepository) EXPECT() *RateRepository_Expecter {
+	return &RateRepository_Expecter{mock: &_m.Mock}
+}
+
+// GetLatestRateSnapshot provides a mock function with no fields
+func (_m *RateRepository) GetLatestRateSnapshot() (domain.RateSnapshot, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestRateSnapshot")
+	}
+
+	var r0 domain.RateSnapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (domain.RateSnapshot, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() domain.RateSnapshot); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(domain.RateSnapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RateRepository_GetLatestRateSnapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLatestRateSnapshot'
+type RateRepository_GetLatestRateSnapshot_Call struct {
+	*mock.Call
+}
+
+// GetLatestRateSnapshot is a helper method to define mock.On call
+func (_e *RateRepository_Expecter) GetLatestRateSnapshot() *RateRepository_GetLatestRateSnapshot_Call {
+	return &RateRepository_GetLatestRateSnapshot_Call{Call: _e.mock.On("GetLatestRateSnapshot")}
+}
+
+func (_c *RateRepository_GetLatestRateSnapshot_Call) Run(run func()) *RateRepository_GetLatestRateSnapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *RateRepository_GetLatestRateSnapshot_Call) Return(_a0 domain.RateSnapshot, _a1 error) *RateRepository_GetLatestRateSnapshot_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *RateRepository_GetLatestRateSnapshot_Call) RunAndReturn(run func() (domain.RateSnapshot, error)) *RateRepository_GetLatestRateSnapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRateSnapshot provides a mock function with given fields: _a0
+func (_m *RateRepository) GetRateSnapshot(_a0 string) (domain.RateSnapshot, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRateSnapshot")
+	}
+
+	var r0 domain.RateSnapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (domain.RateSnapshot, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) domain.RateSnapshot); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(domain.RateSnapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RateRepository_GetRateSnapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRateSnapshot'
+type RateRepository_GetRateSnapshot_Call struct {
+	*mock.Call
+}
+
+// GetRateSnapshot is a helper method to define mock.On call
+//   - _a0 string
+func (_e *RateRepository_Expecter) GetRateSnapshot(_a0 interface{}) *RateRepository_GetRateSnapshot_Call {
+	return &RateRepository_GetRateSnapshot_Call{Call: _e.mock.On("GetRateSnapshot", _a0)}
+}
+
+func (_c *RateRepository_GetRateSnapshot_Call) Run(run func(_a0 string)) *RateRepository_GetRateSnapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *RateRepository_GetRateSnapshot_Call) Return(_a0 domain.RateSnapshot, _a1 error) *RateRepository_GetRateSnapshot_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *RateRepository_GetRateSnapshot_Call) RunAndReturn(run func(string) (domain.RateSnapshot, error)) *RateRepository_GetRateSnapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveRateSnapshot provides a mock function with given fields: _a0
+func (_m *RateRepository) SaveRateSnapshot(_a0 domain.RateSnapshot) (domain.RateSnapshot, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRateSnapshot")
+	}
+
+	var r0 domain.RateSnapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func(domain.RateSnapshot) (domain.RateSnapshot, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(domain.RateSnapshot) domain.RateSnapshot); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(domain.RateSnapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func(domain.RateSnapshot) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RateRepository_SaveRateSnapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveRateSnapshot'
+type RateRepository_SaveRateSnapshot_Call struct {
+	*mock.Call
+}
+
+// SaveRateSnapshot is a helper method to define mock.On call
+//   - _a0 domain.RateSnapshot
+func (_e *RateRepository_Expecter) SaveRateSnapshot(_a0 interface{}) *RateRepository_SaveRateSnapshot_Call {
+	return &RateRepository_SaveRateSnapshot_Call{Call: _e.mock.On("SaveRateSnapshot", _a0)}
+}
+
+func (_c *RateRepository_SaveRateSnapshot_Call) Run(run func(_a0 domain.RateSnapshot)) *RateRepository_SaveRateSnapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(domain.RateSnapshot))
+	})
+	return _c
+}
+
+func (_c *RateRepository_SaveRateSnapshot_Call) Return(_a0 domain.RateSnapshot, _a1 error) *RateRepository_SaveRateSnapshot_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *RateRepository_SaveRateSnapshot_Call) RunAndReturn(run func(domain.RateSnapshot) (domain.RateSnapshot, error)) *RateRepository_SaveRateSnapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewRateRepository creates a new instance of RateRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRateRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RateRepository {
+	mock := &RateRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
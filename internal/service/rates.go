@@ -0,0 +1,108 @@
+package service
+
+import (
+	"maps"
+	"time"
+
+	"github.com/eragon-mdi/calc-back/internal/domain"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+)
+
+//go:generate mockery --name=RateRepository --with-expecter --output=./mocks --exported
+type RateRepository interface {
+	SaveRateSnapshot(domain.RateSnapshot) (domain.RateSnapshot, error)
+	GetLatestRateSnapshot() (domain.RateSnapshot, error)
+	GetRateSnapshot(string) (domain.RateSnapshot, error)
+}
+
+// LoadRates - новая таблица курсов; прежние остаются для уже посчитанных выражений
+func (s service) LoadRates(snapshot domain.RateSnapshot) (domain.RateSnapshot, error) {
+	snapshot, err := normalizeRates(snapshot)
+	if err != nil {
+		return domain.RateSnapshot{}, validationErr(err)
+	}
+
+	snapshot.ID = uuid.NewString()
+	snapshot.CreatedAt = time.Now().UTC()
+
+	snapshot, err = s.rates.SaveRateSnapshot(snapshot)
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, "service: failed to save rates")
+	}
+
+	return snapshot, nil
+}
+
+func (s service) GetLatestRates() (domain.RateSnapshot, error) {
+	snapshot, err := s.rates.GetLatestRateSnapshot()
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, "service: failed to get latest rates")
+	}
+
+	return snapshot, nil
+}
+
+func (s service) GetRatesById(id string) (domain.RateSnapshot, error) {
+	snapshot, err := s.rates.GetRateSnapshot(id)
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, "service: failed to get rates")
+	}
+
+	return snapshot, nil
+}
+
+// normalizeRates - курс базовой валюты 1, его можно не указывать
+func normalizeRates(snapshot domain.RateSnapshot) (domain.RateSnapshot, error) {
+	if !calculable.IsCurrencyCode(snapshot.Base) {
+		return domain.RateSnapshot{}, calculable.ErrInvalidCurrency
+	}
+
+	rates := maps.Clone(snapshot.Rates)
+	if rates == nil {
+		rates = make(map[string]float64)
+	}
+	if rate, ok := rates[snapshot.Base]; ok && rate != 1 {
+		return domain.RateSnapshot{}, calculable.ErrInvalidRate
+	}
+	rates[snapshot.Base] = 1
+
+	if err := calculable.Rates(rates).Validate(); err != nil {
+		return domain.RateSnapshot{}, err
+	}
+
+	return domain.RateSnapshot{Base: snapshot.Base, Rates: rates}, nil
+}
+
+// latestRates - курсы для нового вычисления; пока курсы не загружены, валют в выражениях нет.
+// Выражение без кодов валют от курсов не зависит, и снимок для него не запрашивается.
+func (s service) latestRates(expr string) (domain.RateSnapshot, error) {
+	if s.rates == nil || !calculable.MentionsCurrency(expr) {
+		return domain.RateSnapshot{}, nil
+	}
+
+	snapshot, err := s.rates.GetLatestRateSnapshot()
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.RateSnapshot{}, nil
+	}
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, "service: failed to get latest rates")
+	}
+
+	return snapshot, nil
+}
+
+// storedRates - курсы, по которым посчитано сохранённое вычисление
+func (s service) storedRates(c domain.Calculation) (domain.RateSnapshot, error) {
+	if c.RateSnapshotID == "" || s.rates == nil {
+		return domain.RateSnapshot{}, nil
+	}
+
+	snapshot, err := s.rates.GetRateSnapshot(c.RateSnapshotID)
+	if err != nil {
+		return domain.RateSnapshot{}, errors.Wrap(err, "service: failed to get calc rates")
+	}
+
+	return snapshot, nil
+}
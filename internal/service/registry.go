@@ -0,0 +1,20 @@
+package service
+
+import (
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
+	"github.com/go-faster/errors"
+)
+
+// appFunctions - функции приложения поверх встроенных в calculable, регистрируются в NewRegistry
+var appFunctions []calculable.Function
+
+// NewRegistry - реестр операторов и функций, которым сервис вычисляет выражения
+func NewRegistry() (*calculable.Registry, error) {
+	reg := calculable.NewRegistry()
+	for _, fn := range appFunctions {
+		if err := reg.RegisterFunction(fn); err != nil {
+			return nil, errors.Wrap(err, "service: failed to register function")
+		}
+	}
+	return reg, nil
+}
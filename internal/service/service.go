@@ -1,15 +1,23 @@
 package service
 
 import (
+	"github.com/eragon-mdi/calc-back/internal/common/configs"
 	"github.com/eragon-mdi/calc-back/internal/transport"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
 )
 
 type service struct {
-	r Repository
+	r     Repository
+	rates RateRepository       // nil - выражения без валют
+	reg   *calculable.Registry // nil - только встроенные операторы и функции
+	cfg   *configs.Calculator  // nil - calculable.DefaultLimits без таймаута
 }
 
-func New(r Repository) transport.Service {
+func New(r Repository, rates RateRepository, reg *calculable.Registry, cfg *configs.Calculator) transport.Service {
 	return &service{
-		r: r,
+		r:     r,
+		rates: rates,
+		reg:   reg,
+		cfg:   cfg,
 	}
 }
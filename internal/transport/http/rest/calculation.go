@@ -1,6 +1,7 @@
 package resttransport
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/eragon-mdi/calc-back/internal/domain"
@@ -12,13 +13,19 @@ import (
 type Service interface {
 	GetLastCalculations() ([]domain.Calculation, error)
 	GetCalculationById(domain.CalcID) (domain.Calculation, error)
-	CreateCalculation(domain.CalcExpr) (domain.Calculation, error)
+	CreateCalculation(context.Context, domain.CalcExpr) (domain.Calculation, error)
 	DeleteCalcById(domain.CalcID) error
-	UpdateCalculationById(domain.Calculation) (domain.Calculation, error)
+	UpdateCalculationById(context.Context, domain.Calculation) (domain.Calculation, error)
+	ExplainCalculationById(context.Context, domain.CalcID) (domain.Explanation, error)
+	ExplainExpression(context.Context, domain.CalcExpr) (domain.Explanation, error)
+	LoadRates(domain.RateSnapshot) (domain.RateSnapshot, error)
+	GetLatestRates() (domain.RateSnapshot, error)
+	GetRatesById(string) (domain.RateSnapshot, error)
 }
 
 const (
 	paramID              = "id"
+	headerAcceptLanguage = "Accept-Language"
 	logErrInvalidUUID    = "invalid UUID"
 	logErrInvalidBodyReq = "invalid body request"
 )
@@ -84,8 +91,11 @@ func (t transport) GetCalculationById(c echo.Context) error {
 // @Accept       json
 // @Produce      json
 // @Param        request body CalcRequest true "Данные для вычисления"
+// @Param        Accept-Language header string false "Локаль formatted, если в теле нет locale; на разбор выражения не влияет" example(ru-RU)
 // @Success      201 {object} CalcResponse
-// @Failure 	 400 {object} ErrorResponse
+// @Failure 	 400 {object} ParseErrorResponse "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок"
+// @Failure 	 413 {object} ErrorResponse "выражение длиннее CALC_MAX_LENGTH"
+// @Failure 	 422 {object} ErrorResponse "ошибка вычисления или слишком сложное выражение"
 // @Failure 	 500 {object} ErrorResponse
 // @Router       /calculations [post]
 func (t transport) PostCalculation(c echo.Context) error {
@@ -95,15 +105,15 @@ func (t transport) PostCalculation(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errRespBadRequest)
 	}
 
-	calc, err := t.s.CreateCalculation(calcReq.CalcExpr())
+	calc, err := t.s.CreateCalculation(c.Request().Context(), calcReq.CalcExpr())
 	if err != nil {
 		t.l.Error("transport.PostCalculation failed post calculation", "cause", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, errRespInternal)
+		return httpErrHandler(err)
 	}
 
 	t.l.Info("transport.PostCalculation calculation created successfully", "res", calc)
 
-	return c.JSON(http.StatusCreated, calcResponse(calc))
+	return c.JSON(http.StatusCreated, calcResponseFor(calc, c.Request().Header.Get(headerAcceptLanguage)))
 }
 
 // DeleteCalcById godoc
@@ -133,7 +143,7 @@ func (t transport) DeleteCalcById(c echo.Context) error {
 
 	t.l.Info("transport.DeleteCalcById calculation deleted successfully")
 
-	return c.JSON(http.StatusNoContent, nil)
+	return c.NoContent(http.StatusNoContent)
 }
 
 // PatchCalculationById godoc
@@ -143,9 +153,12 @@ func (t transport) DeleteCalcById(c echo.Context) error {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Индентификатор"
+// @Param        Accept-Language header string false "Локаль formatted, если в теле нет locale; на разбор выражения не влияет" example(ru-RU)
 // @Success      200 {object} CalcResponse
-// @Failure 	 400 {object} ErrorResponse
+// @Failure 	 400 {object} ParseErrorResponse "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок"
 // @Failure 	 404 {object} ErrorResponse
+// @Failure 	 413 {object} ErrorResponse "выражение длиннее CALC_MAX_LENGTH"
+// @Failure 	 422 {object} ErrorResponse "ошибка вычисления или слишком сложное выражение"
 // @Failure 	 500 {object} ErrorResponse
 // @Router       /calculations/{id} [patch]
 func (t transport) PatchCalculationById(c echo.Context) error {
@@ -162,7 +175,7 @@ func (t transport) PatchCalculationById(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errRespBadRequest)
 	}
 
-	calc, err := t.s.UpdateCalculationById(calcReq.Calculation(idStr))
+	calc, err := t.s.UpdateCalculationById(c.Request().Context(), calcReq.Calculation(idStr))
 	if err != nil {
 		t.l.Error("transport.PatchCalculationById failed to update calculation", "cause", err)
 		return httpErrHandler(err)
@@ -170,5 +183,70 @@ func (t transport) PatchCalculationById(c echo.Context) error {
 
 	t.l.Info("transport.PatchCalculationById calculation updated successfully", "res", calc)
 
-	return c.JSON(http.StatusOK, calcResponse(calc))
+	return c.JSON(http.StatusOK, calcResponseFor(calc, c.Request().Header.Get(headerAcceptLanguage)))
+}
+
+// GetExplainById godoc
+// @Summary      Шаги вычисления
+// @Description  Повторяет сохранённое вычисление и возвращает его шаги в порядке выполнения. Индентификатор - строковой тип UUID
+// @Tags         calculations
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Индентификатор"
+// @Param        Accept-Language header string false "Локаль formatted, если вычисление записано без локали" example(ru-RU)
+// @Success      200 {object} ExplainResponse
+// @Failure 	 400 {object} ErrorResponse
+// @Failure 	 404 {object} ErrorResponse
+// @Failure 	 422 {object} ErrorResponse
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /calculations/{id}/explain [get]
+func (t transport) GetExplainById(c echo.Context) error {
+	idStr := c.Param(paramID)
+
+	if err := uuid.Validate(idStr); err != nil {
+		t.l.Error("transport.GetExplainById", logErrInvalidUUID, "cause", err)
+		return echo.NewHTTPError(http.StatusBadRequest, errRespBadIdParam)
+	}
+
+	explanation, err := t.s.ExplainCalculationById(c.Request().Context(), calcId(idStr))
+	if err != nil {
+		t.l.Error("transport.GetExplainById failed to explain calculation", "cause", err)
+		return httpErrHandler(err)
+	}
+
+	t.l.Info("transport.GetExplainById calculation explained successfully", "res", explanation.Calculation)
+
+	return c.JSON(http.StatusOK, explainResponse(explanation, c.Request().Header.Get(headerAcceptLanguage)))
+}
+
+// PostExplain godoc
+// @Summary      Шаги вычисления выражения
+// @Description  Вычисляет выражение без сохранения и возвращает шаги в порядке выполнения
+// @Tags         calculations
+// @Accept       json
+// @Produce      json
+// @Param        request body CalcRequest true "Данные для вычисления"
+// @Param        Accept-Language header string false "Локаль formatted, если в теле нет locale; на разбор выражения не влияет" example(ru-RU)
+// @Success      200 {object} ExplainResponse
+// @Failure 	 400 {object} ParseErrorResponse "ошибка в выражении; offset, token, expected и caret только у синтаксических ошибок"
+// @Failure 	 413 {object} ErrorResponse "выражение длиннее CALC_MAX_LENGTH"
+// @Failure 	 422 {object} ErrorResponse "ошибка вычисления или слишком сложное выражение"
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /calculations/explain [post]
+func (t transport) PostExplain(c echo.Context) error {
+	var calcReq CalcRequest
+	if err := c.Bind(&calcReq); err != nil {
+		t.l.Error("transport.PostExplain", logErrInvalidBodyReq, "cause", err)
+		return echo.NewHTTPError(http.StatusBadRequest, errRespBadRequest)
+	}
+
+	explanation, err := t.s.ExplainExpression(c.Request().Context(), calcReq.CalcExpr())
+	if err != nil {
+		t.l.Error("transport.PostExplain failed to explain expression", "cause", err)
+		return httpErrHandler(err)
+	}
+
+	t.l.Info("transport.PostExplain expression explained successfully", "res", explanation.Calculation)
+
+	return c.JSON(http.StatusOK, explainResponse(explanation, c.Request().Header.Get(headerAcceptLanguage)))
 }
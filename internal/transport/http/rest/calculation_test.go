@@ -1,16 +1,22 @@
 package resttransport
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/eragon-mdi/calc-back/internal/domain"
 	"github.com/eragon-mdi/calc-back/internal/transport/http/rest/mocks"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 )
 
@@ -208,13 +214,14 @@ func Test_transport_PostCalculation(t *testing.T) {
 		fields  fields
 		args    args
 		wantErr bool
+		check   func(t *testing.T, err error)
 	}{
 		{
 			name: "successful case",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
-					ms.EXPECT().CreateCalculation(domain.CalcExpr{Expr: "1+1"}).
+					ms.EXPECT().CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1"}).
 						Return(domain.Calculation{ID: "1", Expression: "1+1", Result: "2"}, nil)
 					return ms
 				},
@@ -231,6 +238,52 @@ func Test_transport_PostCalculation(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Accept-Language does not set the expression locale",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "max(1,2)"}).
+						Return(domain.Calculation{ID: "1", Expression: "max(1,2)", CanonicalExpression: "max(1,2)", Result: "2"}, nil)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"max(1,2)"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					req.Header.Set(headerAcceptLanguage, "ja;q=1, ru-RU;q=0.9, en;q=0.8")
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
+				}(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "locale in body overrides Accept-Language",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1.5", Locale: "en"}).
+						Return(domain.Calculation{ID: "1", Expression: "1.5", CanonicalExpression: "1.5", Locale: "en", Result: "1.5"}, nil)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1.5","locale":"en"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					req.Header.Set(headerAcceptLanguage, "ru")
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
+				}(),
+			},
+			wantErr: false,
+		},
 		{
 			name: "bad request - invalid JSON",
 			fields: fields{
@@ -253,7 +306,7 @@ func Test_transport_PostCalculation(t *testing.T) {
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
-					ms.EXPECT().CreateCalculation(domain.CalcExpr{Expr: "1+1"}).
+					ms.EXPECT().CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1"}).
 						Return(domain.Calculation{}, errors.New("service error"))
 					return ms
 				},
@@ -271,13 +324,13 @@ func Test_transport_PostCalculation(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "validation error from service",
+			name: "variables are passed to service",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
 					ms.EXPECT().
-						CreateCalculation(domain.CalcExpr{Expr: "1+1"}).
-						Return(domain.Calculation{}, domain.ErrValidation)
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "pi*r^2", Variables: map[string]float64{"r": 2}}).
+						Return(domain.Calculation{ID: "1", Expression: "pi*r^2", Variables: map[string]float64{"r": 2}, Result: "12.566370614359172"}, nil)
 					return ms
 				},
 				l: logger,
@@ -285,302 +338,1302 @@ func Test_transport_PostCalculation(t *testing.T) {
 			args: args{
 				c: func() echo.Context {
 					e := echo.New()
-					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+1"}`))
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"pi*r^2","variables":{"r":2}}`))
 					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 					rec := httptest.NewRecorder()
 					return e.NewContext(req, rec)
 				}(),
 			},
-			wantErr: true,
+			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tr := transport{
-				s: tt.fields.s(),
-				l: tt.fields.l,
-			}
-			if err := tr.PostCalculation(tt.args.c); (err != nil) != tt.wantErr {
-				t.Errorf("transport.PostCalculation() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_transport_DeleteCalcById(t *testing.T) {
-	type fields struct {
-		s func() Service
-		l *zap.SugaredLogger
-	}
-	type args struct {
-		c echo.Context
-	}
-	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
-	}{
 		{
-			name: "successful case",
+			name: "precision options are passed to service",
 			fields: fields{
 				s: func() Service {
+					scale := 2
 					ms := mocks.NewService(t)
-					ms.EXPECT().DeleteCalcById(domain.CalcID{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e"}).
-						Return(nil)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{
+							Expr:      "0.1+0.2",
+							Precision: domain.Precision{Mode: "exact", Scale: &scale, Rounding: "half-up"},
+						}).
+						Return(domain.Calculation{ID: "1", Expression: "0.1+0.2", Result: "0.30"}, nil)
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodDelete, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					return ctx
+					e := echo.New()
+					body := `{"expression":"0.1+0.2","precision":"exact","scale":2,"rounding":"half-up"}`
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(body))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: false,
 		},
 		{
-			name: "invalid UUID",
+			name: "integer options are passed to service",
 			fields: fields{
-				s: func() Service { return nil },
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{
+							Expr:      "0xF0 | 0x0F",
+							Precision: domain.Precision{Mode: "integer", IntType: "uint8", Overflow: "saturate"},
+						}).
+						Return(domain.Calculation{ID: "1", Expression: "0xF0 | 0x0F", Result: "255"}, nil)
+					return ms
+				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodDelete, "/calculations/invalid-uuid")
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("invalid-uuid")
-					return ctx
+					e := echo.New()
+					body := `{"expression":"0xF0 | 0x0F","precision":"integer","int_type":"uint8","overflow":"saturate"}`
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(body))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "service error - not found",
+			name: "units flag is passed to service",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
-					ms.EXPECT().DeleteCalcById(domain.CalcID{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e"}).
-						Return(domain.ErrNotFound)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "5 km in mi", Units: true}).
+						Return(domain.Calculation{ID: "1", Expression: "5 km in mi", Result: "3.106855961187376", Units: true, Unit: "mi"}, nil)
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodDelete, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					return ctx
+					e := echo.New()
+					body := `{"expression":"5 km in mi","units":true}`
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(body))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
-			wantErr: true,
+			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tr := transport{
-				s: tt.fields.s(),
-				l: tt.fields.l,
-			}
-			if err := tr.DeleteCalcById(tt.args.c); (err != nil) != tt.wantErr {
-				t.Errorf("transport.DeleteCalcById() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_transport_PatchCalculationById(t *testing.T) {
-	type fields struct {
-		s func() Service
-		l *zap.SugaredLogger
-	}
-	type args struct {
-		c echo.Context
-	}
-	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
-		check   func(t *testing.T, err error)
-	}{
 		{
-			name: "successful case",
+			name: "format options are passed to service",
 			fields: fields{
 				s: func() Service {
+					digits := 3
 					ms := mocks.NewService(t)
 					ms.EXPECT().
-						UpdateCalculationById(domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
-						Return(domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2", Result: "3"}, nil)
+						CreateCalculation(mock.Anything, domain.CalcExpr{
+							Expr:   "1e6/3",
+							Format: domain.Format{Digits: &digits, Notation: "scientific", Grouping: true},
+						}).
+						Return(domain.Calculation{ID: "1", Expression: "1e6/3", Result: "333333.3333333333"}, nil)
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					body := `{"expression":"1e6/3","format":{"digits":3,"notation":"scientific","grouping":true}}`
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(body))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: false,
 		},
 		{
-			name: "invalid UUID",
+			name: "undefined variable error from service",
 			fields: fields{
-				s: func() Service { return nil },
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "pi*r^2"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.VariableError{
+							Name: "r", Err: calculable.ErrUndefinedVariable,
+						}))
+					return ms
+				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/invalid-uuid", `{"expression":"1+2"}`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("invalid-uuid")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"pi*r^2"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: true,
 			check: func(t *testing.T, err error) {
 				httpErr, ok := err.(*echo.HTTPError)
-				if !ok || httpErr.Code != http.StatusBadRequest {
-					t.Errorf("expected 400 Bad Request, got: %v", err)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
+				}
+				want := ErrorResponse{"undefined variable: r"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
 				}
 			},
 		},
 		{
-			name: "invalid JSON",
+			name: "function arity error from service",
 			fields: fields{
-				s: func() Service { return nil },
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "sqrt(1,2)"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.FunctionError{
+							Name: "sqrt", Err: calculable.ErrArgumentCount, Detail: "expects 1, got 2",
+						}))
+					return ms
+				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"sqrt(1,2)"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: true,
 			check: func(t *testing.T, err error) {
 				httpErr, ok := err.(*echo.HTTPError)
 				if !ok || httpErr.Code != http.StatusBadRequest {
-					t.Errorf("expected 400 Bad Request, got: %v", err)
+					t.Fatalf("expected 400 Bad Request, got: %v", err)
+				}
+				want := ErrorResponse{"invalid number of function arguments: sqrt expects 1, got 2"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
 				}
 			},
 		},
 		{
-			name: "not found from service",
+			name: "function domain error from service",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
 					ms.EXPECT().
-						UpdateCalculationById(domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
-						Return(domain.Calculation{}, domain.ErrNotFound)
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "sqrt(-1)"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.FunctionError{
+							Name: "sqrt", Err: calculable.ErrFunctionDomain,
+						}))
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"sqrt(-1)"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: true,
 			check: func(t *testing.T, err error) {
 				httpErr, ok := err.(*echo.HTTPError)
-				if !ok || httpErr.Code != http.StatusNotFound {
-					t.Errorf("expected 404 Not Found, got: %v", err)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
+				}
+				want := ErrorResponse{"math error: argument out of function domain: sqrt"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
 				}
 			},
 		},
 		{
-			name: "internal error from service",
+			name: "too long expression from service",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
 					ms.EXPECT().
-						UpdateCalculationById(domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
-						Return(domain.Calculation{}, errors.New("unexpected error"))
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1+1"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.LimitError{
+							Limit: calculable.LimitLength, Max: 4,
+						}))
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+1+1"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: true,
 			check: func(t *testing.T, err error) {
 				httpErr, ok := err.(*echo.HTTPError)
-				if !ok || httpErr.Code != http.StatusInternalServerError {
-					t.Errorf("expected 500 Internal Server Error, got: %v", err)
+				if !ok || httpErr.Code != http.StatusRequestEntityTooLarge {
+					t.Fatalf("expected 413 Request Entity Too Large, got: %v", err)
+				}
+				want := ErrorResponse{"expression is too complex: length exceeds 4"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
 				}
 			},
 		},
 		{
-			name: "validation error from service",
+			name: "too complex expression from service",
 			fields: fields{
 				s: func() Service {
 					ms := mocks.NewService(t)
 					ms.EXPECT().
-						UpdateCalculationById(domain.Calculation{
-							ID:         "a8098c1a-f86e-11da-bd1a-00112444be1e",
-							Expression: "1+2",
-						}).
-						Return(domain.Calculation{}, domain.ErrValidation)
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1+1"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.LimitError{
+							Limit: calculable.LimitOperations, Max: 1,
+						}))
 					return ms
 				},
 				l: logger,
 			},
 			args: args{
 				c: func() echo.Context {
-					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
-					ctx.SetParamNames("id")
-					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
-					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-					return ctx
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+1+1"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
 				}(),
 			},
 			wantErr: true,
 			check: func(t *testing.T, err error) {
 				httpErr, ok := err.(*echo.HTTPError)
-				if !ok || httpErr.Code != http.StatusBadRequest {
-					t.Errorf("expected 400 Bad Request, got: %v", err)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
 				}
-				if httpErr.Message != errRespValidation {
-					t.Errorf("expected error message %v, got %v", errRespValidation, httpErr.Message)
+				want := ErrorResponse{"expression is too complex: operations exceeds 1"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
 				}
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tr := transport{
-				s: tt.fields.s(),
-				l: tt.fields.l,
-			}
-			err := tr.PatchCalculationById(tt.args.c)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("transport.PatchCalculationById() error = %v, wantErr %v", err, tt.wantErr)
+		{
+			name: "evaluation timeout from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1+1"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, context.DeadlineExceeded))
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+1+1"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
+				}
+				want := ErrorResponse{"expression is too complex: evaluation timed out"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
+				}
+			},
+		},
+		{
+			name: "parse error from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+*2"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.ParseError{
+							Err:        calculable.ErrConsecutiveOperators,
+							Expression: "1+*2",
+							Offset:     2,
+							ByteOffset: 2,
+							Token:      "*",
+							Expected:   []string{"number", "("},
+						}))
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+*2"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400 Bad Request, got: %v", err)
+				}
+				want := ParseErrorResponse{
+					Message:    "invalid format: consecutive operators at offset 2",
+					Offset:     2,
+					ByteOffset: 2,
+					Token:      "*",
+					Expected:   []string{"number", "("},
+					Caret:      "1+*2\n  ^",
+				}
+				if !reflect.DeepEqual(httpErr.Message, want) {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
+				}
+			},
+		},
+		{
+			name: "validation error from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						CreateCalculation(mock.Anything, domain.CalcExpr{Expr: "1+1"}).
+						Return(domain.Calculation{}, domain.ErrValidation)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					e := echo.New()
+					req := httptest.NewRequest(http.MethodPost, "/calculations", strings.NewReader(`{"expression":"1+1"}`))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					rec := httptest.NewRecorder()
+					return e.NewContext(req, rec)
+				}(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.fields.s(),
+				l: tt.fields.l,
+			}
+			err := tr.PostCalculation(tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("transport.PostCalculation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+		})
+	}
+}
+
+func Test_transport_DeleteCalcById(t *testing.T) {
+	type fields struct {
+		s func() Service
+		l *zap.SugaredLogger
+	}
+	type args struct {
+		c echo.Context
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "successful case",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().DeleteCalcById(domain.CalcID{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e"}).
+						Return(nil)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodDelete, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					return ctx
+				}(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid UUID",
+			fields: fields{
+				s: func() Service { return nil },
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodDelete, "/calculations/invalid-uuid")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("invalid-uuid")
+					return ctx
+				}(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "service error - not found",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().DeleteCalcById(domain.CalcID{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e"}).
+						Return(domain.ErrNotFound)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodDelete, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					return ctx
+				}(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.fields.s(),
+				l: tt.fields.l,
+			}
+			if err := tr.DeleteCalcById(tt.args.c); (err != nil) != tt.wantErr {
+				t.Errorf("transport.DeleteCalcById() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_transport_PatchCalculationById(t *testing.T) {
+	type fields struct {
+		s func() Service
+		l *zap.SugaredLogger
+	}
+	type args struct {
+		c echo.Context
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "successful case",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
+						Return(domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2", Result: "3"}, nil)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid UUID",
+			fields: fields{
+				s: func() Service { return nil },
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/invalid-uuid", `{"expression":"1+2"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("invalid-uuid")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Errorf("expected 400 Bad Request, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "invalid JSON",
+			fields: fields{
+				s: func() Service { return nil },
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Errorf("expected 400 Bad Request, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "not found from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
+						Return(domain.Calculation{}, domain.ErrNotFound)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusNotFound {
+					t.Errorf("expected 404 Not Found, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "internal error from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1+2"}).
+						Return(domain.Calculation{}, errors.New("unexpected error"))
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusInternalServerError {
+					t.Errorf("expected 500 Internal Server Error, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "division by zero from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "1/0"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, calculable.ErrDivisionByZero))
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1/0"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
+				}
+				want := ErrorResponse{"math error: division by zero"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
+				}
+			},
+		},
+		{
+			name: "function overflow from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: "exp(1000)"}).
+						Return(domain.Calculation{}, fmt.Errorf("%w: %w", domain.ErrValidation, &calculable.FunctionError{
+							Name: "exp", Err: calculable.ErrOverflow,
+						}))
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"exp(1000)"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected 422 Unprocessable Entity, got: %v", err)
+				}
+				want := ErrorResponse{"math error: result is too large: exp"}
+				if httpErr.Message != want {
+					t.Errorf("expected error message %v, got %v", want, httpErr.Message)
+				}
+			},
+		},
+		{
+			name: "validation error from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().
+						UpdateCalculationById(mock.Anything, domain.Calculation{
+							ID:         "a8098c1a-f86e-11da-bd1a-00112444be1e",
+							Expression: "1+2",
+						}).
+						Return(domain.Calculation{}, domain.ErrValidation)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodPatch, "/calculations/a8098c1a-f86e-11da-bd1a-00112444be1e", `{"expression":"1+2"}`)
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+					ctx.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Errorf("expected 400 Bad Request, got: %v", err)
+				}
+				if httpErr.Message != errRespValidation {
+					t.Errorf("expected error message %v, got %v", errRespValidation, httpErr.Message)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.fields.s(),
+				l: tt.fields.l,
+			}
+			err := tr.PatchCalculationById(tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("transport.PatchCalculationById() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+		})
+	}
+}
+
+func Test_calcResponse(t *testing.T) {
+	two := 2
+
+	tests := []struct {
+		name string
+		calc domain.Calculation
+		want CalcResponse
+	}{
+		{
+			name: "float result has no other bases",
+			calc: domain.Calculation{ID: "1", Expression: "1+2", Precision: domain.Precision{Mode: "float"}, Result: "3"},
+			want: CalcResponse{ID: "1", Expression: "1+2", Precision: "float", Result: "3", Value: json.Number("3")},
+		},
+		{
+			name: "boolean result",
+			calc: domain.Calculation{
+				ID: "1", Expression: "x >= 10", Variables: map[string]float64{"x": 12}, Precision: domain.Precision{Mode: "float"},
+				Result: "true", ResultType: "boolean",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "x >= 10", Variables: map[string]float64{"x": 12}, Precision: "float",
+				Result: "true", ResultType: "boolean", Value: true,
+			},
+		},
+		{
+			name: "number result is a JSON number",
+			calc: domain.Calculation{ID: "1", Expression: "x >= 10 ? 1.5 : 0", Precision: domain.Precision{Mode: "float"}, Result: "1.5", ResultType: "number"},
+			want: CalcResponse{ID: "1", Expression: "x >= 10 ? 1.5 : 0", Precision: "float", Result: "1.5", ResultType: "number", Value: json.Number("1.5")},
+		},
+		{
+			name: "integer result in hex and binary",
+			calc: domain.Calculation{
+				ID: "1", Expression: "0x7F + 1",
+				Precision: domain.Precision{Mode: "integer", IntType: "int8", Overflow: "wrap"},
+				Result:    "-128",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "0x7F + 1",
+				Precision: "integer", IntType: "int8", Overflow: "wrap",
+				Result: "-128", Value: json.Number("-128"), Hex: "0x80", Binary: "0b10000000",
+			},
+		},
+		{
+			name: "complex result in algebraic and polar form",
+			calc: domain.Calculation{
+				ID: "1", Expression: "3+4i", Precision: domain.Precision{Mode: "complex"}, Result: "3+4i",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "3+4i", Precision: "complex", Result: "3+4i", Value: "3+4i",
+				Complex: &ComplexResponse{Re: 3, Im: 4, Abs: 5, Arg: 0.9272952180016122},
+			},
+		},
+		{
+			name: "formatted result",
+			calc: domain.Calculation{
+				ID: "1", Expression: "1e6/3", Precision: domain.Precision{Mode: "float"},
+				Format: domain.Format{Decimals: &two, Rounding: "half-even", Notation: "plain", Grouping: true},
+				Result: "333333.3333333333",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "1e6/3", Precision: "float", Result: "333333.3333333333", Value: json.Number("333333.3333333333"),
+				Format:    &ResultFormat{Decimals: &two, Rounding: "half-even", Notation: "plain", Grouping: true},
+				Formatted: "333,333.33",
+			},
+		},
+		{
+			name: "localized result without format",
+			calc: domain.Calculation{
+				ID: "1", Expression: "3,5 + 1 000", CanonicalExpression: "3.5 + 1_000", Locale: "ru",
+				Precision: domain.Precision{Mode: "float"}, Result: "1003.5",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "3,5 + 1 000", CanonicalExpression: "3.5 + 1_000", Locale: "ru",
+				Precision: "float", Result: "1003.5", Value: json.Number("1003.5"), Formatted: "1003,5",
+			},
+		},
+		{
+			name: "result with unit",
+			calc: domain.Calculation{
+				ID: "1", Expression: "5 km + 300 m", Locale: "ru",
+				Precision: domain.Precision{Mode: "float"}, Result: "5.3", Units: true, Unit: "km",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "5 km + 300 m", Locale: "ru",
+				Precision: "float", Result: "5.3", Value: json.Number("5.3"), Units: true, Unit: "km", Formatted: "5,3 km",
+			},
+		},
+		{
+			name: "normalized expression",
+			calc: domain.Calculation{
+				ID: "1", Expression: "6 ÷ 2", NormalizedExpression: "6 / 2", CanonicalExpression: "6 / 2",
+				Precision: domain.Precision{Mode: "float"}, Result: "3",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "6 ÷ 2", NormalizedExpression: "6 / 2", CanonicalExpression: "6 / 2",
+				Precision: "float", Result: "3", Value: json.Number("3"),
+			},
+		},
+		{
+			name: "fraction result",
+			calc: domain.Calculation{
+				ID: "1", Expression: "3 + 1/2", Precision: domain.Precision{Mode: "exact", Output: "fraction", Scale: &two, Rounding: "half-even"},
+				Result: "7/2",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "3 + 1/2", Precision: "exact", Output: "fraction", Scale: &two, Rounding: "half-even", Result: "7/2", Value: "7/2",
+				Fraction: &FractionResponse{Numerator: "7", Denominator: "2", Mixed: "3 1/2", Decimal: "3.50"},
+			},
+		},
+		{
+			name: "negative fraction with locale",
+			calc: domain.Calculation{
+				ID: "1", Expression: "-1/3", Locale: "ru", Precision: domain.Precision{Mode: "exact", Output: "fraction", Rounding: "half-even"},
+				Result: "-1/3",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "-1/3", Locale: "ru", Precision: "exact", Output: "fraction", Rounding: "half-even", Result: "-1/3", Value: "-1/3",
+				Formatted: "-0,33333333333333333333",
+				Fraction:  &FractionResponse{Numerator: "-1", Denominator: "3", Mixed: "-1/3", Decimal: "-0.33333333333333333333"},
+			},
+		},
+		{
+			name: "localized grouping",
+			calc: domain.Calculation{
+				ID: "1", Expression: "1e6/3", Locale: "de", Precision: domain.Precision{Mode: "float"},
+				Format: domain.Format{Decimals: &two, Rounding: "half-even", Notation: "plain", Grouping: true},
+				Result: "333333.3333333333",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "1e6/3", Locale: "de", Precision: "float", Result: "333333.3333333333", Value: json.Number("333333.3333333333"),
+				Format:    &ResultFormat{Decimals: &two, Rounding: "half-even", Notation: "plain", Grouping: true},
+				Formatted: "333.333,33",
+			},
+		},
+		{
+			name: "formatted integer result",
+			calc: domain.Calculation{
+				ID: "1", Expression: "255", Precision: domain.Precision{Mode: "integer", IntType: "uint8", Overflow: "wrap"},
+				Format: domain.Format{Digits: &two, Rounding: "half-even", Notation: "scientific"},
+				Result: "255",
+			},
+			want: CalcResponse{
+				ID: "1", Expression: "255", Precision: "integer", IntType: "uint8", Overflow: "wrap", Result: "255", Value: json.Number("255"),
+				Format:    &ResultFormat{Digits: &two, Rounding: "half-even", Notation: "scientific"},
+				Formatted: "2.6e2", Hex: "0xff", Binary: "0b11111111",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcResponse(tt.calc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calcResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_preferredLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "empty", acceptLanguage: "", want: ""},
+		{name: "single", acceptLanguage: "ru-RU", want: "ru"},
+		{name: "highest q", acceptLanguage: "en;q=0.5, de-DE;q=0.8, fr;q=0.7", want: "de"},
+		{name: "first of equal q", acceptLanguage: "uk, ru", want: "uk"},
+		{name: "unsupported skipped", acceptLanguage: "ja, zh;q=0.9, ru;q=0.1", want: "ru"},
+		{name: "wildcard", acceptLanguage: "*", want: ""},
+		{name: "zero q", acceptLanguage: "ru;q=0", want: ""},
+		{name: "invalid q", acceptLanguage: "ru;q=x, en;q=0.2", want: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferredLocale(tt.acceptLanguage); got != tt.want {
+				t.Errorf("preferredLocale(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_transport_GetExplainById(t *testing.T) {
+	const id = "a8098c1a-f86e-11da-bd1a-00112444be1e"
+
+	type fields struct {
+		s func() Service
+		l *zap.SugaredLogger
+	}
+	type args struct {
+		c echo.Context
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "successful case",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().ExplainCalculationById(mock.Anything, domain.CalcID{ID: id}).
+						Return(domain.Explanation{
+							Calculation: domain.Calculation{ID: id, Expression: "1+1", Result: "2"},
+							Steps:       []domain.Step{{Expression: "1+1", Operator: "+", Operands: []string{"1", "1"}, Result: "2"}},
+						}, nil)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodGet, "/calculations/"+id+"/explain")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues(id)
+					return ctx
+				}(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "failed validate id",
+			fields: fields{
+				s: func() Service { return nil },
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodGet, "/calculations/a8098c1a/explain")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues("a8098c1a")
+					return ctx
+				}(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "failed service case: notFound",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().ExplainCalculationById(mock.Anything, domain.CalcID{ID: id}).
+						Return(domain.Explanation{}, domain.ErrNotFound)
+					return ms
+				},
+				l: logger,
+			},
+			args: args{
+				c: func() echo.Context {
+					ctx := newEchoCtx(http.MethodGet, "/calculations/"+id+"/explain")
+					ctx.SetParamNames("id")
+					ctx.SetParamValues(id)
+					return ctx
+				}(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.fields.s(),
+				l: tt.fields.l,
+			}
+			if err := tr.GetExplainById(tt.args.c); (err != nil) != tt.wantErr {
+				t.Errorf("transport.GetExplainById() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_transport_PostExplain(t *testing.T) {
+	type fields struct {
+		s func() Service
+		l *zap.SugaredLogger
+	}
+	type args struct {
+		body string
+	}
+	tests := []struct {
+		name     string
+		fields   fields
+		args     args
+		wantErr  bool
+		wantCode int
+	}{
+		{
+			name: "successful case",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().ExplainExpression(mock.Anything, domain.CalcExpr{Expr: "(1+2)*3"}).
+						Return(domain.Explanation{
+							Calculation: domain.Calculation{Expression: "(1+2)*3", Result: "9"},
+							Steps:       []domain.Step{},
+						}, nil)
+					return ms
+				},
+				l: logger,
+			},
+			args:     args{body: `{"expression":"(1+2)*3"}`},
+			wantErr:  false,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "bad request - invalid JSON",
+			fields: fields{
+				s: func() Service { return nil },
+				l: logger,
+			},
+			args:     args{body: `{"expression":`},
+			wantErr:  true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "math error from service",
+			fields: fields{
+				s: func() Service {
+					ms := mocks.NewService(t)
+					ms.EXPECT().ExplainExpression(mock.Anything, domain.CalcExpr{Expr: "1/0"}).
+						Return(domain.Explanation{}, fmt.Errorf("%w: %w", domain.ErrValidation, calculable.ErrDivisionByZero))
+					return ms
+				},
+				l: logger,
+			},
+			args:     args{body: `{"expression":"1/0"}`},
+			wantErr:  true,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.fields.s(),
+				l: tt.fields.l,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/calculations/explain", strings.NewReader(tt.args.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			err := tr.PostExplain(echo.New().NewContext(req, rec))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("transport.PostExplain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			code := rec.Code
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				code = httpErr.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("transport.PostExplain() code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func Test_transport_AcceptLanguageFormatsOutput(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expression     string
+		result         string
+		wantFormatted  string
+	}{
+		{name: "ru arguments", acceptLanguage: "ru-RU", expression: "max(1,2) + min(3,10)", result: "5", wantFormatted: "5"},
+		{name: "ru logarithm base", acceptLanguage: "ru-RU,ru;q=0.9", expression: "log(100,10)", result: "2", wantFormatted: "2"},
+		{name: "de rounding digits", acceptLanguage: "de", expression: "round(2.567,2)", result: "2.57", wantFormatted: "2,57"},
+		{name: "de decimal point", acceptLanguage: "de", expression: "1.000 + 1", result: "2", wantFormatted: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := domain.Calculation{ID: "1", Expression: tt.expression, Result: tt.result}
+			ms := mocks.NewService(t)
+			// выражение уходит в сервис без локали, то есть разбирается с запятой между аргументами
+			ms.EXPECT().CreateCalculation(mock.Anything, domain.CalcExpr{Expr: tt.expression}).Return(calc, nil).Once()
+			ms.EXPECT().UpdateCalculationById(mock.Anything, domain.Calculation{ID: "a8098c1a-f86e-11da-bd1a-00112444be1e", Expression: tt.expression}).Return(calc, nil).Once()
+			ms.EXPECT().ExplainExpression(mock.Anything, domain.CalcExpr{Expr: tt.expression}).Return(domain.Explanation{Calculation: calc}, nil).Once()
+			tr := transport{s: ms, l: logger}
+
+			for _, call := range []struct {
+				method  string
+				handler func(echo.Context) error
+			}{
+				{method: http.MethodPost, handler: tr.PostCalculation},
+				{method: http.MethodPatch, handler: tr.PatchCalculationById},
+				{method: http.MethodPost, handler: tr.PostExplain},
+			} {
+				body, _ := json.Marshal(CalcRequest{Expression: tt.expression})
+				req := httptest.NewRequest(call.method, "/calculations", strings.NewReader(string(body)))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(headerAcceptLanguage, tt.acceptLanguage)
+				rec := httptest.NewRecorder()
+				ctx := echo.New().NewContext(req, rec)
+				ctx.SetParamNames("id")
+				ctx.SetParamValues("a8098c1a-f86e-11da-bd1a-00112444be1e")
+
+				if err := call.handler(ctx); err != nil {
+					t.Fatalf("handler error = %v", err)
+				}
+				var resp CalcResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("json.Unmarshal() error = %v", err)
+				}
+				if resp.Locale != "" || resp.Formatted != tt.wantFormatted {
+					t.Errorf("response locale = %q, formatted = %q, want no locale and %q", resp.Locale, resp.Formatted, tt.wantFormatted)
+				}
+			}
+		})
+	}
+}
+
+func Test_explainResponse(t *testing.T) {
+	got := explainResponse(domain.Explanation{
+		Calculation: domain.Calculation{ID: "1", Expression: "2*3+1", Precision: domain.Precision{Mode: "float"}, Result: "7", ResultType: "number"},
+		Steps: []domain.Step{
+			{Expression: "2*3", Operator: "*", Operands: []string{"2", "3"}, Result: "6"},
+			{Expression: "2*3+1", Operator: "+", Operands: []string{"6", "1"}, Result: "7"},
+			{Expression: "7 + 10%", Operator: "+%", Operands: []string{"7", "10"}, Result: "7.7", Interpretation: "7 + 7 * 10 / 100"},
+		},
+	}, "")
+
+	want := ExplainResponse{
+		CalcResponse: CalcResponse{ID: "1", Expression: "2*3+1", Precision: "float", Result: "7", ResultType: "number", Value: json.Number("7")},
+		Steps: []StepResponse{
+			{Expression: "2*3", Operator: "*", Operands: []string{"2", "3"}, Result: "6"},
+			{Expression: "2*3+1", Operator: "+", Operands: []string{"6", "1"}, Result: "7"},
+			{Expression: "7 + 10%", Operator: "+%", Operands: []string{"7", "10"}, Result: "7.7", Interpretation: "7 + 7 * 10 / 100"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explainResponse() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_transport_LoadRates(t *testing.T) {
+	saved := domain.RateSnapshot{ID: "rates-1", Base: "RUB", Rates: map[string]float64{"RUB": 1, "USD": 90, "EUR": 100}}
+
+	newCtx := func(method, uri, contentType, body string) echo.Context {
+		e := echo.New()
+		req := httptest.NewRequest(method, uri, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		return e.NewContext(req, httptest.NewRecorder())
+	}
+
+	tests := []struct {
+		name    string
+		s       func() Service
+		handler func(transport, echo.Context) error
+		c       echo.Context
+		wantErr bool
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "json table",
+			s: func() Service {
+				ms := mocks.NewService(t)
+				ms.EXPECT().LoadRates(domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"USD": 90, "EUR": 100}}).
+					Return(saved, nil)
+				return ms
+			},
+			handler: transport.PutRates,
+			c:       newCtx(http.MethodPut, "/rates", echo.MIMEApplicationJSON, `{"base":"RUB","rates":{"USD":90,"EUR":100}}`),
+			wantErr: false,
+		},
+		{
+			name: "csv with header",
+			s: func() Service {
+				ms := mocks.NewService(t)
+				ms.EXPECT().LoadRates(domain.RateSnapshot{Base: "RUB", Rates: map[string]float64{"USD": 90, "EUR": 100}}).
+					Return(saved, nil)
+				return ms
+			},
+			handler: transport.PostRatesCSV,
+			c:       newCtx(http.MethodPost, "/rates/import?base=RUB", "text/csv", "currency,rate\nUSD,90\nEUR, 100\n"),
+			wantErr: false,
+		},
+		{
+			name:    "csv with bad rate",
+			s:       func() Service { return mocks.NewService(t) },
+			handler: transport.PostRatesCSV,
+			c:       newCtx(http.MethodPost, "/rates/import?base=RUB", "text/csv", "USD,ninety\n"),
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400 Bad Request, got: %v", err)
+				}
+			},
+		},
+		{
+			name:    "csv with duplicate currency",
+			s:       func() Service { return mocks.NewService(t) },
+			handler: transport.PostRatesCSV,
+			c:       newCtx(http.MethodPost, "/rates/import?base=RUB", "text/csv", "currency,rate\nUSD,90\nEUR,100\nUSD, 91\n"),
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400 Bad Request, got: %v", err)
+				}
+				if resp, ok := httpErr.Message.(ErrorResponse); !ok || !strings.Contains(resp.Message, "duplicate currency USD on line 4") {
+					t.Errorf("expected duplicate USD on line 4, got: %v", httpErr.Message)
+				}
+			},
+		},
+		{
+			name: "invalid table from service",
+			s: func() Service {
+				ms := mocks.NewService(t)
+				ms.EXPECT().LoadRates(mock.Anything).
+					Return(domain.RateSnapshot{}, fmt.Errorf("%w: %w", domain.ErrValidation, calculable.ErrInvalidRate))
+				return ms
+			},
+			handler: transport.PutRates,
+			c:       newCtx(http.MethodPut, "/rates", echo.MIMEApplicationJSON, `{"base":"RUB","rates":{"USD":-1}}`),
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok || httpErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400 Bad Request, got: %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transport{
+				s: tt.s(),
+				l: logger,
+			}
+			err := tt.handler(tr, tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("transport.LoadRates() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			if tt.check != nil {
 				tt.check(t, err)
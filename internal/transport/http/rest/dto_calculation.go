@@ -1,32 +1,163 @@
 package resttransport
 
 import (
+	"encoding/json"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+	"strings"
+
 	"github.com/eragon-mdi/calc-back/internal/domain"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
 )
 
 type CalcRequest struct {
-	Expression string `json:"expression" example:"2+3/2"`
+	Expression string             `json:"expression" example:"2+3/2"`
+	Locale     string             `json:"locale,omitempty" example:"ru"` // запись чисел в выражении, без неё - без локали
+	Variables  map[string]float64 `json:"variables,omitempty"`
+	Precision  string             `json:"precision,omitempty" enums:"float,exact,integer,complex" example:"exact"`
+	Output     string             `json:"output,omitempty" enums:"decimal,fraction" example:"fraction"` // fraction без precision - exact
+	Scale      *int               `json:"scale,omitempty" example:"2"`
+	Rounding   string             `json:"rounding,omitempty" enums:"half-even,half-up,half-down,up,truncate,ceiling,floor" example:"half-even"`
+	IntType    string             `json:"int_type,omitempty" enums:"int8,int16,int32,int64,uint8,uint16,uint32,uint64" example:"int64"`
+	Overflow   string             `json:"overflow,omitempty" enums:"wrap,saturate,error" example:"wrap"`
+	Format     *ResultFormat      `json:"format,omitempty"`
+	Units      bool               `json:"units,omitempty" example:"true"` // единицы измерения: "5 km + 300 m in mi"
+}
+
+// ResultFormat - вид результата в поле formatted; digits и decimals взаимоисключающие
+type ResultFormat struct {
+	Digits   *int   `json:"digits,omitempty" example:"3"`
+	Decimals *int   `json:"decimals,omitempty" example:"2"`
+	Rounding string `json:"rounding,omitempty" enums:"half-even,half-up,half-down,up,truncate,ceiling,floor" example:"half-up"`
+	Notation string `json:"notation,omitempty" enums:"plain,scientific,engineering" example:"plain"`
+	Grouping bool   `json:"grouping,omitempty" example:"true"`
 }
 
 type CalcResponse struct {
-	ID         string `json:"id" example:"a8098c1a-f86e-11da-bd1a-00112444be1e"`
-	Expression string `json:"expression" example:"2+3/2"`
-	Result     string `json:"result" example:"3.5"`
+	ID                   string             `json:"id" example:"a8098c1a-f86e-11da-bd1a-00112444be1e"`
+	Expression           string             `json:"expression" example:"2+3/2"`
+	NormalizedExpression string             `json:"normalized_expression,omitempty" example:"2+3/2"`
+	CanonicalExpression  string             `json:"canonical_expression,omitempty" example:"2+3/2"`
+	Locale               string             `json:"locale,omitempty" example:"ru"`
+	Variables            map[string]float64 `json:"variables,omitempty"`
+	Precision            string             `json:"precision" example:"exact"`
+	Output               string             `json:"output,omitempty" example:"fraction"`
+	Scale                *int               `json:"scale,omitempty" example:"2"`
+	Rounding             string             `json:"rounding,omitempty" example:"half-even"`
+	IntType              string             `json:"int_type,omitempty" example:"int64"`
+	Overflow             string             `json:"overflow,omitempty" example:"wrap"`
+	Result               string             `json:"result" example:"3.5"`
+	ResultType           string             `json:"result_type" enums:"number,boolean" example:"number"`
+	Value                any                `json:"value"` // result в типе result_type: 3.5 или true; дробь и комплексное число - строкой
+	Units                bool               `json:"units,omitempty" example:"true"`
+	Unit                 string             `json:"unit,omitempty" example:"km"`
+	RateSnapshotID       string             `json:"rate_snapshot_id,omitempty" example:"0b7c2c9e-5f0e-4a53-9d43-0f1d1a8b6c11"`
+	Format               *ResultFormat      `json:"format,omitempty"`
+	Formatted            string             `json:"formatted,omitempty" example:"3.50"`
+	Fraction             *FractionResponse  `json:"fraction,omitempty"`
+	Complex              *ComplexResponse   `json:"complex,omitempty"`
+	Hex                  string             `json:"hex,omitempty" example:"0xff"`
+	Binary               string             `json:"binary,omitempty" example:"0b11111111"`
+}
+
+// FractionResponse - точный результат в output fraction: дробь, смешанная дробь и десятичная запись
+// по scale и rounding
+type FractionResponse struct {
+	Numerator   string `json:"numerator" example:"7"`
+	Denominator string `json:"denominator" example:"2"`
+	Mixed       string `json:"mixed" example:"3 1/2"`
+	Decimal     string `json:"decimal" example:"3.5"`
+}
+
+// ComplexResponse - результат режима complex: алгебраическая и тригонометрическая формы
+type ComplexResponse struct {
+	Re  float64 `json:"re" example:"11"`
+	Im  float64 `json:"im" example:"-2"`
+	Abs float64 `json:"abs" example:"11.180339887498949"`
+	Arg float64 `json:"arg" example:"-0.17985349979247828"`
+}
+
+// ExplainResponse - вычисление и шаги, которыми получен результат
+type ExplainResponse struct {
+	CalcResponse
+	Steps []StepResponse `json:"steps"`
+}
+
+type StepResponse struct {
+	Expression string   `json:"expression" example:"(1+2)*3"`
+	Operator   string   `json:"operator" example:"*"`
+	Operands   []string `json:"operands" example:"3,3"`
+	Result     string   `json:"result" example:"9"`
+	// Interpretation - только у процентных операций: "200 + 10%" - "200 + 200 * 10 / 100"
+	Interpretation string `json:"interpretation,omitempty" example:"200 + 200 * 10 / 100"`
 }
 
 type ErrorResponse struct {
 	Message string `json:"error" example:"<cause>"`
 }
 
+// ParseErrorResponse - синтаксическая ошибка с местом в выражении, offset в рунах
+type ParseErrorResponse struct {
+	Message    string   `json:"error" example:"invalid format: consecutive operators at offset 4"`
+	Offset     int      `json:"offset" example:"4"`
+	ByteOffset int      `json:"byte_offset" example:"4"`
+	Token      string   `json:"token" example:"*"`
+	Expected   []string `json:"expected,omitempty" example:"number,identifier,(,+,-"`
+	Caret      string   `json:"caret" example:"1 + * 2\n    ^"`
+}
+
+func parseErrorResponse(e *calculable.ParseError) ParseErrorResponse {
+	return ParseErrorResponse{
+		Message:    e.Error(),
+		Offset:     e.Offset,
+		ByteOffset: e.ByteOffset,
+		Token:      e.Token,
+		Expected:   e.Expected,
+		Caret:      e.Caret(),
+	}
+}
+
 func calcId(id string) domain.CalcID {
 	return domain.CalcID{
 		ID: id,
 	}
 }
 
+// preferredLocale - поддерживаемый язык с наибольшим q из Accept-Language: "ru-RU,ru;q=0.9,en;q=0.8" - "ru".
+// Без поддерживаемых языков - пустая строка, то есть запись без локали.
+func preferredLocale(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		locale, err := calculable.ParseLocale(tag)
+		if err == nil && q > bestQ {
+			best, bestQ = string(locale), q
+		}
+	}
+	return best
+}
+
 func (c CalcRequest) CalcExpr() domain.CalcExpr {
 	return domain.CalcExpr{
-		Expr: c.Expression,
+		Expr:      c.Expression,
+		Locale:    c.Locale,
+		Variables: c.Variables,
+		Precision: c.precision(),
+		Format:    c.format(),
+		Units:     c.Units,
 	}
 }
 
@@ -34,15 +165,165 @@ func (c CalcRequest) Calculation(id string) domain.Calculation {
 	return domain.Calculation{
 		ID:         id,
 		Expression: c.Expression,
+		Locale:     c.Locale,
+		Variables:  c.Variables,
+		Precision:  c.precision(),
+		Format:     c.format(),
+		Units:      c.Units,
+	}
+}
+
+func (c CalcRequest) format() domain.Format {
+	if c.Format == nil {
+		return domain.Format{}
+	}
+	return domain.Format(*c.Format)
+}
+
+func (c CalcRequest) precision() domain.Precision {
+	return domain.Precision{
+		Mode:     c.Precision,
+		Output:   c.Output,
+		Scale:    c.Scale,
+		Rounding: c.Rounding,
+		IntType:  c.IntType,
+		Overflow: c.Overflow,
 	}
 }
 
 func calcResponse(c domain.Calculation) CalcResponse {
-	return CalcResponse{
-		ID:         c.ID,
-		Expression: c.Expression,
-		Result:     c.Result,
+	return localizedCalcResponse(c, c.Locale)
+}
+
+// calcResponseFor - formatted в локали из Accept-Language, если выражение записано без локали.
+// Заголовок влияет только на вывод: выражение разбирается по полю locale, и с ru-RU "max(1,2)" - это max(1, 2).
+func calcResponseFor(c domain.Calculation, acceptLanguage string) CalcResponse {
+	if c.Locale != "" {
+		return calcResponse(c)
 	}
+	return localizedCalcResponse(c, preferredLocale(acceptLanguage))
+}
+
+// localizedCalcResponse - ответ с formatted в локали display
+func localizedCalcResponse(c domain.Calculation, display string) CalcResponse {
+	resp := CalcResponse{
+		ID:                   c.ID,
+		Expression:           c.Expression,
+		NormalizedExpression: c.NormalizedExpression,
+		CanonicalExpression:  c.CanonicalExpression,
+		Locale:               c.Locale,
+		Variables:            c.Variables,
+		Precision:            c.Precision.Mode,
+		Output:               c.Precision.Output,
+		Scale:                c.Precision.Scale,
+		Rounding:             c.Precision.Rounding,
+		IntType:              c.Precision.IntType,
+		Overflow:             c.Precision.Overflow,
+		Result:               c.Result,
+		ResultType:           c.ResultType,
+		Value:                resultValue(c),
+		Units:                c.Units,
+		Unit:                 c.Unit,
+		RateSnapshotID:       c.RateSnapshotID,
+	}
+
+	if c.Format != (domain.Format{}) {
+		format := ResultFormat(c.Format)
+		resp.Format = &format
+	}
+	// с локалью результат для человека есть и без формата: 3,5 вместо 3.5
+	if c.Format != (domain.Format{}) || display != "" {
+		resp.Formatted = formatted(c.Result, c.Format, display)
+	}
+	if resp.Formatted != "" && c.Unit != "" {
+		resp.Formatted += " " + c.Unit
+	}
+
+	if c.Precision.Output == string(calculable.OutputFraction) {
+		resp.Fraction = fractionResponse(c.Result, c.Precision)
+	}
+
+	if c.Precision.Mode == string(calculable.PrecisionComplex) {
+		resp.Complex = complexResponse(c.Result)
+	}
+
+	// у целочисленного результата дополнительно шестнадцатеричная и двоичная запись
+	if c.Precision.Mode == string(calculable.PrecisionInteger) {
+		if n, ok := new(big.Int).SetString(c.Result, 10); ok {
+			intType := calculable.IntType(c.Precision.IntType)
+			resp.Hex = calculable.FormatInteger(n, intType, 16)
+			resp.Binary = calculable.FormatInteger(n, intType, 2)
+		}
+	}
+
+	return resp
+}
+
+// formatted - результат в виде для человека; пусто, если результат не число
+func formatted(result string, f domain.Format, locale string) string {
+	r, ok := new(big.Rat).SetString(result)
+	if !ok {
+		return ""
+	}
+
+	format := calculable.NumberFormat{
+		Decimals: calculable.AutoScale,
+		Rounding: calculable.RoundingMode(f.Rounding),
+		Notation: calculable.Notation(f.Notation),
+		Grouping: f.Grouping,
+		Locale:   calculable.Locale(locale),
+	}
+	if f.Digits != nil {
+		format.Digits = *f.Digits
+	}
+	if f.Decimals != nil {
+		format.Decimals = *f.Decimals
+	}
+	return calculable.FormatNumber(r, format)
+}
+
+// resultValue - результат JSON-значением своего типа; что не записывается числом JSON
+// (7/2, 3+4i), остаётся строкой
+func resultValue(c domain.Calculation) any {
+	if c.ResultType == string(calculable.ValueBoolean) {
+		if b, err := strconv.ParseBool(c.Result); err == nil {
+			return b
+		}
+		return c.Result
+	}
+
+	if _, err := strconv.ParseFloat(c.Result, 64); err == nil && json.Valid([]byte(c.Result)) {
+		return json.Number(c.Result)
+	}
+	return c.Result
+}
+
+// fractionResponse - nil, если результат не дробь
+func fractionResponse(result string, p domain.Precision) *FractionResponse {
+	r, ok := new(big.Rat).SetString(result)
+	if !ok {
+		return nil
+	}
+
+	scale := calculable.AutoScale
+	if p.Scale != nil {
+		scale = *p.Scale
+	}
+	return &FractionResponse{
+		Numerator:   r.Num().String(),
+		Denominator: r.Denom().String(),
+		Mixed:       calculable.FormatMixed(r),
+		Decimal:     calculable.FormatDecimal(r, scale, calculable.RoundingMode(p.Rounding)),
+	}
+}
+
+// complexResponse - nil, если результат не комплексное число
+func complexResponse(result string) *ComplexResponse {
+	z, err := strconv.ParseComplex(result, 128)
+	if err != nil {
+		return nil
+	}
+	return &ComplexResponse{Re: real(z), Im: imag(z), Abs: cmplx.Abs(z), Arg: cmplx.Phase(z)}
 }
 
 func calcsResponse(cs []domain.Calculation) []CalcResponse {
@@ -52,3 +333,14 @@ func calcsResponse(cs []domain.Calculation) []CalcResponse {
 	}
 	return res
 }
+
+func explainResponse(e domain.Explanation, acceptLanguage string) ExplainResponse {
+	resp := ExplainResponse{
+		CalcResponse: calcResponseFor(e.Calculation, acceptLanguage),
+		Steps:        make([]StepResponse, 0, len(e.Steps)),
+	}
+	for _, step := range e.Steps {
+		resp.Steps = append(resp.Steps, StepResponse(step))
+	}
+	return resp
+}
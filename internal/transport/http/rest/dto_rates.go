@@ -0,0 +1,80 @@
+package resttransport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eragon-mdi/calc-back/internal/domain"
+)
+
+// RatesRequest - таблица курсов: стоимость единицы валюты в base; курс base можно не указывать
+type RatesRequest struct {
+	Base  string             `json:"base" example:"RUB"`
+	Rates map[string]float64 `json:"rates" example:"USD:92.5,EUR:100.1"`
+}
+
+type RatesResponse struct {
+	ID        string             `json:"id" example:"0b7c2c9e-5f0e-4a53-9d43-0f1d1a8b6c11"`
+	Base      string             `json:"base" example:"RUB"`
+	Rates     map[string]float64 `json:"rates" example:"RUB:1,USD:92.5,EUR:100.1"`
+	CreatedAt time.Time          `json:"created_at" example:"2026-10-18T18:00:00Z"`
+}
+
+var (
+	errCSVRow       = errors.New("csv row must be: currency,rate")
+	errCSVDuplicate = errors.New("duplicate currency")
+)
+
+func (r RatesRequest) RateSnapshot() domain.RateSnapshot {
+	return domain.RateSnapshot{
+		Base:  r.Base,
+		Rates: r.Rates,
+	}
+}
+
+func ratesResponse(s domain.RateSnapshot) RatesResponse {
+	return RatesResponse{
+		ID:        s.ID,
+		Base:      s.Base,
+		Rates:     s.Rates,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// parseRatesCSV - строки "currency,rate", первая строка может быть заголовком "currency,rate".
+// Повтор валюты - ошибка с кодом и номером строки: какой из курсов верный, неизвестно.
+func parseRatesCSV(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	rates := make(map[string]float64)
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 0 && strings.EqualFold(record[0], "currency") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, errCSVRow
+		}
+		code := strings.TrimSpace(record[0])
+		if _, seen := rates[code]; seen {
+			row, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%w %s on line %d", errCSVDuplicate, code, row)
+		}
+		rates[code] = rate
+	}
+}
@@ -1,10 +1,12 @@
 package resttransport
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
 	"github.com/eragon-mdi/calc-back/internal/domain"
+	calculable "github.com/eragon-mdi/calc-back/pkg/math/calcualte"
 	"github.com/labstack/echo/v4"
 )
 
@@ -14,10 +16,54 @@ var (
 	errRespBadIdParam = ErrorResponse{"Bad id param"}
 	errRespBadRequest = ErrorResponse{"Bad request"}
 	errRespValidation = ErrorResponse{"Failed on validation"}
+	errRespTimeout    = ErrorResponse{"expression is too complex: evaluation timed out"}
 )
 
+// mathErrs - выражение записано верно, но результат не определён или не представим
+var mathErrs = []error{
+	calculable.ErrDivisionByZero,
+	calculable.ErrModuloByZero,
+	calculable.ErrNegativeBasePower,
+	calculable.ErrOverflow,
+	calculable.ErrNotANumber,
+	calculable.ErrFunctionDomain,
+	calculable.ErrInexact,
+	calculable.ErrExponentTooLarge,
+	calculable.ErrIntegerOverflow,
+	calculable.ErrNotInteger,
+	calculable.ErrNegativeShift,
+	calculable.ErrNotReal,
+	calculable.ErrDimensionMismatch,
+}
+
 func httpErrHandler(err error) error {
+	var (
+		parseErr *calculable.ParseError
+		fnErr    *calculable.FunctionError
+		varErr   *calculable.VariableError
+		limitErr *calculable.LimitError
+	)
+
+	if cause, ok := mathErr(err); ok {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, ErrorResponse{cause.Error()})
+	}
+
 	switch {
+	// слишком длинное выражение - слишком большой запрос, остальные ограничения - сложность выражения
+	case errors.As(err, &limitErr) && limitErr.Limit == calculable.LimitLength:
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, ErrorResponse{limitErr.Error()})
+	case errors.As(err, &limitErr):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, ErrorResponse{limitErr.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, errRespTimeout)
+	case errors.As(err, &parseErr):
+		return echo.NewHTTPError(http.StatusBadRequest, parseErrorResponse(parseErr))
+	case errors.As(err, &fnErr):
+		return echo.NewHTTPError(http.StatusBadRequest, ErrorResponse{fnErr.Error()})
+	case errors.As(err, &varErr) && errors.Is(varErr, calculable.ErrUndefinedVariable):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, ErrorResponse{varErr.Error()})
+	case errors.As(err, &varErr):
+		return echo.NewHTTPError(http.StatusBadRequest, ErrorResponse{varErr.Error()})
 	case errors.Is(err, domain.ErrNotFound):
 		return echo.NewHTTPError(http.StatusNotFound, errRespNotFound)
 	case errors.Is(err, domain.ErrValidation):
@@ -26,3 +72,20 @@ func httpErrHandler(err error) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, errRespInternal)
 	}
 }
+
+// mathErr - причина для ответа клиенту; у ошибки функции сохраняется её имя
+func mathErr(err error) (error, bool) {
+	for _, target := range mathErrs {
+		if !errors.Is(err, target) {
+			continue
+		}
+
+		var fnErr *calculable.FunctionError
+		if errors.As(err, &fnErr) {
+			return fnErr, true
+		}
+		return target, true
+	}
+
+	return nil, false
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	domain "github.com/eragon-mdi/calc-back/internal/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -20,9 +22,9 @@ func (_m *Service) EXPECT() *Service_Expecter {
 	return &Service_Expecter{mock: &_m.Mock}
 }
 
-// CreateCalculation provides a mock function with given fields: _a0
-func (_m *Service) CreateCalculation(_a0 domain.CalcExpr) (domain.Calculation, error) {
-	ret := _m.Called(_a0)
+// CreateCalculation provides a mock function with given fields: _a0, _a1
+func (_m *Service) CreateCalculation(_a0 context.Context, _a1 domain.CalcExpr) (domain.Calculation, error) {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateCalculation")
@@ -30,17 +32,17 @@ func (_m *Service) CreateCalculation(_a0 domain.CalcExpr) (domain.Calculation, e
 
 	var r0 domain.Calculation
 	var r1 error
-	if rf, ok := ret.Get(0).(func(domain.CalcExpr) (domain.Calculation, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcExpr) (domain.Calculation, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(domain.CalcExpr) domain.Calculation); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcExpr) domain.Calculation); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Get(0).(domain.Calculation)
 	}
 
-	if rf, ok := ret.Get(1).(func(domain.CalcExpr) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.CalcExpr) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,14 +56,15 @@ type Service_CreateCalculation_Call struct {
 }
 
 // CreateCalculation is a helper method to define mock.On call
-//   - _a0 domain.CalcExpr
-func (_e *Service_Expecter) CreateCalculation(_a0 interface{}) *Service_CreateCalculation_Call {
-	return &Service_CreateCalculation_Call{Call: _e.mock.On("CreateCalculation", _a0)}
+//   - _a0 context.Context
+//   - _a1 domain.CalcExpr
+func (_e *Service_Expecter) CreateCalculation(_a0 interface{}, _a1 interface{}) *Service_CreateCalculation_Call {
+	return &Service_CreateCalculation_Call{Call: _e.mock.On("CreateCalculation", _a0, _a1)}
 }
 
-func (_c *Service_CreateCalculation_Call) Run(run func(_a0 domain.CalcExpr)) *Service_CreateCalculation_Call {
+func (_c *Service_CreateCalculation_Call) Run(run func(_a0 context.Context, _a1 domain.CalcExpr)) *Service_CreateCalculation_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(domain.CalcExpr))
+		run(args[0].(context.Context), args[1].(domain.CalcExpr))
 	})
 	return _c
 }
@@ -71,7 +74,7 @@ func (_c *Service_CreateCalculation_Call) Return(_a0 domain.Calculation, _a1 err
 	return _c
 }
 
-func (_c *Service_CreateCalculation_Call) RunAndReturn(run func(domain.CalcExpr) (domain.Calculation, error)) *Service_CreateCalculation_Call {
+func (_c *Service_CreateCalculation_Call) RunAndReturn(run func(context.Context, domain.CalcExpr) (domain.Calculation, error)) *Service_CreateCalculation_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -122,6 +125,120 @@ func (_c *Service_DeleteCalcById_Call) RunAndReturn(run func(domain.CalcID) erro
 	return _c
 }
 
+// ExplainCalculationById provides a mock function with given fields: _a0, _a1
+func (_m *Service) ExplainCalculationById(_a0 context.Context, _a1 domain.CalcID) (domain.Explanation, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExplainCalculationById")
+	}
+
+	var r0 domain.Explanation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcID) (domain.Explanation, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcID) domain.Explanation); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.Explanation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.CalcID) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Service_ExplainCalculationById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExplainCalculationById'
+type Service_ExplainCalculationById_Call struct {
+	*mock.Call
+}
+
+// ExplainCalculationById is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.CalcID
+func (_e *Service_Expecter) ExplainCalculationById(_a0 interface{}, _a1 interface{}) *Service_ExplainCalculationById_Call {
+	return &Service_ExplainCalculationById_Call{Call: _e.mock.On("ExplainCalculationById", _a0, _a1)}
+}
+
+func (_c *Service_ExplainCalculationById_Call) Run(run func(_a0 context.Context, _a1 domain.CalcID)) *Service_ExplainCalculationById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.CalcID))
+	})
+	return _c
+}
+
+func (_c *Service_ExplainCalculationById_Call) Return(_a0 domain.Explanation, _a1 error) *Service_ExplainCalculationById_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Service_ExplainCalculationById_Call) RunAndReturn(run func(context.Context, domain.CalcID) (domain.Explanation, error)) *Service_ExplainCalculationById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExplainExpression provides a mock function with given fields: _a0, _a1
+func (_m *Service) ExplainExpression(_a0 context.Context, _a1 domain.CalcExpr) (domain.Explanation, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExplainExpression")
+	}
+
+	var r0 domain.Explanation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcExpr) (domain.Explanation, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.CalcExpr) domain.Explanation); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.Explanation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.CalcExpr) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Service_ExplainExpression_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExplainExpression'
+type Service_ExplainExpression_Call struct {
+	*mock.Call
+}
+
+// ExplainExpression is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 domain.CalcExpr
+func (_e *Service_Expecter) ExplainExpression(_a0 interface{}, _a1 interface{}) *Service_ExplainExpression_Call {
+	return &Service_ExplainExpression_Call{Call: _e.mock.On("ExplainExpression", _a0, _a1)}
+}
+
+func (_c *Service_ExplainExpression_Call) Run(run func(_a0 context.Context, _a1 domain.CalcExpr)) *Service_ExplainExpression_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.CalcExpr))
+	})
+	return _c
+}
+
+func (_c *Service_ExplainExpression_Call) Return(_a0 domain.Explanation, _a1 error) *Service_ExplainExpression_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Service_ExplainExpression_Call) RunAndReturn(run func(context.Context, domain.CalcExpr) (domain.Explanation, error)) *Service_ExplainExpression_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetCalculationById provides a mock function with given fields: _a0
 func (_m *Service) GetCalculationById(_a0 domain.CalcID) (domain.Calculation, error) {
 	ret := _m.Called(_a0)
@@ -235,26 +352,81 @@ func (_c *Service_GetLastCalculations_Call) RunAndReturn(run func() ([]domain.Ca
 	return _c
 }
 
-// UpdateCalculationById provides a mock function with given fields: _a0
-func (_m *Service) UpdateCalculationById(_a0 domain.Calculation) (domain.Calculation, error) {
+// GetLatestRates provides a mock function with no fields
+func (_m *Service) GetLatestRates() (domain.RateSnapshot, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestRates")
+	}
+
+	var r0 domain.RateSnapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (domain.RateSnapshot, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() domain.RateSnapshot); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(domain.RateSnapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Service_GetLatestRates_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLatestRates'
+type Service_GetLatestRates_Call struct {
+	*mock.Call
+}
+
+// GetLatestRates is a helper method to define mock.On call
+func (_e *Service_Expecter) GetLatestRates() *Service_GetLatestRates_Call {
+	return &Service_GetLatestRates_Call{Call: _e.mock.On("GetLatestRates")}
+}
+
+func (_c *Service_GetLatestRates_Call) Run(run func()) *Service_GetLatestRates_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Service_GetLatestRates_Call) Return(_a0 domain.RateSnapshot, _a1 error) *Service_GetLatestRates_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Service_GetLatestRates_Call) RunAndReturn(run func() (domain.RateSnapshot, error)) *Service_GetLatestRates_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRatesById provides a mock function with given fields: _a0
+func (_m *Service) GetRatesById(_a0 string) (domain.RateSnapshot, error) {
 	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateCalculationById")
+		panic("no return value specified for GetRatesById")
 	}
 
-	var r0 domain.Calculation
+	var r0 domain.RateSnapshot
 	var r1 error
-	if rf, ok := ret.Get(0).(func(domain.Calculation) (domain.Calculation, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (domain.RateSnapshot, error)); ok {
 		return rf(_a0)
 	}
-	if rf, ok := ret.Get(0).(func(domain.Calculation) domain.Calculation); ok {
+	if rf, ok := ret.Get(0).(func(string) domain.RateSnapshot); ok {
 		r0 = rf(_a0)
 	} else {
-		r0 = ret.Get(0).(domain.Calculation)
+		r0 = ret.Get(0).(domain.RateSnapshot)
 	}
 
-	if rf, ok := ret.Get(1).(func(domain.Calculation) error); ok {
+	if rf, ok := ret.Get(1).(func(string) error); ok {
 		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
@@ -263,20 +435,133 @@ func (_m *Service) UpdateCalculationById(_a0 domain.Calculation) (domain.Calcula
 	return r0, r1
 }
 
+// Service_GetRatesById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRatesById'
+type Service_GetRatesById_Call struct {
+	*mock.Call
+}
+
+// GetRatesById is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Service_Expecter) GetRatesById(_a0 interface{}) *Service_GetRatesById_Call {
+	return &Service_GetRatesById_Call{Call: _e.mock.On("GetRatesById", _a0)}
+}
+
+func (_c *Service_GetRatesById_Call) Run(run func(_a0 string)) *Service_GetRatesById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Service_GetRatesById_Call) Return(_a0 domain.RateSnapshot, _a1 error) *Service_GetRatesById_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Service_GetRatesById_Call) RunAndReturn(run func(string) (domain.RateSnapshot, error)) *Service_GetRatesById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LoadRates provides a mock function with given fields: _a0
+func (_m *Service) LoadRates(_a0 domain.RateSnapshot) (domain.RateSnapshot, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoadRates")
+	}
+
+	var r0 domain.RateSnapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func(domain.RateSnapshot) (domain.RateSnapshot, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(domain.RateSnapshot) domain.RateSnapshot); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(domain.RateSnapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func(domain.RateSnapshot) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Service_LoadRates_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LoadRates'
+type Service_LoadRates_Call struct {
+	*mock.Call
+}
+
+// LoadRates is a helper method to define mock.On call
+//   - _a0 domain.RateSnapshot
+func (_e *Service_Expecter) LoadRates(_a0 interface{}) *Service_LoadRates_Call {
+	return &Service_LoadRates_Call{Call: _e.mock.On("LoadRates", _a0)}
+}
+
+func (_c *Service_LoadRates_Call) Run(run func(_a0 domain.RateSnapshot)) *Service_LoadRates_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(domain.RateSnapshot))
+	})
+	return _c
+}
+
+func (_c *Service_LoadRates_Call) Return(_a0 domain.RateSnapshot, _a1 error) *Service_LoadRates_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Service_LoadRates_Call) RunAndReturn(run func(domain.RateSnapshot) (domain.RateSnapshot, error)) *Service_LoadRates_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateCalculationById provides a mock function with given fields: _a0, _a1
+func (_m *Service) UpdateCalculationById(_a0 context.Context, _a1 domain.Calculation) (domain.Calculation, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateCalculationById")
+	}
+
+	var r0 domain.Calculation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Calculation) (domain.Calculation, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Calculation) domain.Calculation); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(domain.Calculation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Calculation) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Service_UpdateCalculationById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateCalculationById'
 type Service_UpdateCalculationById_Call struct {
 	*mock.Call
 }
 
 // UpdateCalculationById is a helper method to define mock.On call
-//   - _a0 domain.Calculation
-func (_e *Service_Expecter) UpdateCalculationById(_a0 interface{}) *Service_UpdateCalculationById_Call {
-	return &Service_UpdateCalculationById_Call{Call: _e.mock.On("UpdateCalculationById", _a0)}
+//   - _a0 context.Context
+//   - _a1 domain.Calculation
+func (_e *Service_Expecter) UpdateCalculationById(_a0 interface{}, _a1 interface{}) *Service_UpdateCalculationById_Call {
+	return &Service_UpdateCalculationById_Call{Call: _e.mock.On("UpdateCalculationById", _a0, _a1)}
 }
 
-func (_c *Service_UpdateCalculationById_Call) Run(run func(_a0 domain.Calculation)) *Service_UpdateCalculationById_Call {
+func (_c *Service_UpdateCalculationById_Call) Run(run func(_a0 context.Context, _a1 domain.Calculation)) *Service_UpdateCalculationById_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(domain.Calculation))
+		run(args[0].(context.Context), args[1].(domain.Calculation))
 	})
 	return _c
 }
@@ -286,7 +571,7 @@ func (_c *Service_UpdateCalculationById_Call) Return(_a0 domain.Calculation, _a1
 	return _c
 }
 
-func (_c *Service_UpdateCalculationById_Call) RunAndReturn(run func(domain.Calculation) (domain.Calculation, error)) *Service_UpdateCalculationById_Call {
+func (_c *Service_UpdateCalculationById_Call) RunAndReturn(run func(context.Context, domain.Calculation) (domain.Calculation, error)) *Service_UpdateCalculationById_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,109 @@
+package resttransport
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const queryBase = "base"
+
+// GetLatestRates godoc
+// @Summary      Текущие курсы валют
+// @Description  Возвращает последнюю загруженную таблицу курсов, по ней считаются новые выражения
+// @Tags         rates
+// @Produce      json
+// @Success      200 {object} RatesResponse
+// @Failure 	 404 {object} ErrorResponse "курсы не загружены"
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /rates [get]
+func (t transport) GetLatestRates(c echo.Context) error {
+	rates, err := t.s.GetLatestRates()
+	if err != nil {
+		t.l.Error("transport.GetLatestRates failed to get rates", "cause", err)
+		return httpErrHandler(err)
+	}
+
+	t.l.Info("transport.GetLatestRates rates getted successfully", "res", rates.ID)
+
+	return c.JSON(http.StatusOK, ratesResponse(rates))
+}
+
+// GetRatesById godoc
+// @Summary      Таблица курсов по идентификатору
+// @Description  Возвращает таблицу курсов, на которую ссылается rate_snapshot_id вычисления
+// @Tags         rates
+// @Produce      json
+// @Param        id path string true "Индентификатор"
+// @Success      200 {object} RatesResponse
+// @Failure 	 404 {object} ErrorResponse
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /rates/{id} [get]
+func (t transport) GetRatesById(c echo.Context) error {
+	rates, err := t.s.GetRatesById(c.Param(paramID))
+	if err != nil {
+		t.l.Error("transport.GetRatesById failed to get rates", "cause", err)
+		return httpErrHandler(err)
+	}
+
+	t.l.Info("transport.GetRatesById rates getted successfully", "res", rates.ID)
+
+	return c.JSON(http.StatusOK, ratesResponse(rates))
+}
+
+// PutRates godoc
+// @Summary      Загрузить курсы валют
+// @Description  Создаёт новую таблицу курсов; прежние сохраняются для уже посчитанных выражений. Нужен заголовок Authorization: Bearer с MIDDLEWARE_ADMIN_TOKEN
+// @Tags         rates
+// @Accept       json
+// @Produce      json
+// @Param        request body RatesRequest true "Курсы"
+// @Success      201 {object} RatesResponse
+// @Failure 	 400 {object} ErrorResponse
+// @Failure 	 401 {object} ErrorResponse
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /rates [put]
+func (t transport) PutRates(c echo.Context) error {
+	var ratesReq RatesRequest
+	if err := c.Bind(&ratesReq); err != nil {
+		t.l.Error("transport.PutRates", logErrInvalidBodyReq, "cause", err)
+		return echo.NewHTTPError(http.StatusBadRequest, errRespBadRequest)
+	}
+
+	return t.loadRates(c, "transport.PutRates", ratesReq)
+}
+
+// PostRatesCSV godoc
+// @Summary      Импорт курсов из CSV
+// @Description  Тело - строки "currency,rate", первая строка может быть заголовком. Базовая валюта - в параметре base. Нужен заголовок Authorization: Bearer с MIDDLEWARE_ADMIN_TOKEN
+// @Tags         rates
+// @Accept       text/csv
+// @Produce      json
+// @Param        base query string true "Базовая валюта" example(RUB)
+// @Param        request body string true "CSV с курсами"
+// @Success      201 {object} RatesResponse
+// @Failure 	 400 {object} ErrorResponse
+// @Failure 	 401 {object} ErrorResponse
+// @Failure 	 500 {object} ErrorResponse
+// @Router       /rates/import [post]
+func (t transport) PostRatesCSV(c echo.Context) error {
+	rates, err := parseRatesCSV(c.Request().Body)
+	if err != nil {
+		t.l.Error("transport.PostRatesCSV", logErrInvalidBodyReq, "cause", err)
+		return echo.NewHTTPError(http.StatusBadRequest, ErrorResponse{err.Error()})
+	}
+
+	return t.loadRates(c, "transport.PostRatesCSV", RatesRequest{Base: c.QueryParam(queryBase), Rates: rates})
+}
+
+func (t transport) loadRates(c echo.Context, op string, ratesReq RatesRequest) error {
+	rates, err := t.s.LoadRates(ratesReq.RateSnapshot())
+	if err != nil {
+		t.l.Error(op+" failed to load rates", "cause", err)
+		return httpErrHandler(err)
+	}
+
+	t.l.Info(op+" rates loaded successfully", "res", rates.ID)
+
+	return c.JSON(http.StatusCreated, ratesResponse(rates))
+}
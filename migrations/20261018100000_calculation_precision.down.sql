@@ -0,0 +1,4 @@
+ALTER TABLE calculations
+    DROP COLUMN precision_mode,
+    DROP COLUMN precision_scale,
+    DROP COLUMN precision_rounding;
@@ -0,0 +1,4 @@
+ALTER TABLE calculations
+    ADD COLUMN precision_mode TEXT NOT NULL DEFAULT 'float',
+    ADD COLUMN precision_scale INTEGER,
+    ADD COLUMN precision_rounding TEXT NOT NULL DEFAULT '';
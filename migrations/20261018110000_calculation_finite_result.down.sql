@@ -0,0 +1 @@
+ALTER TABLE calculations DROP CONSTRAINT calculations_result_finite;
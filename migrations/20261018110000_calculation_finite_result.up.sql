@@ -0,0 +1,4 @@
+-- старые строки с "+Inf"/"NaN" не проверяются, новые такими быть не могут
+ALTER TABLE calculations
+    ADD CONSTRAINT calculations_result_finite
+    CHECK (result NOT IN ('+Inf', '-Inf', 'NaN')) NOT VALID;
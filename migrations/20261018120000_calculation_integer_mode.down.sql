@@ -0,0 +1,3 @@
+ALTER TABLE calculations
+    DROP COLUMN precision_int_type,
+    DROP COLUMN precision_overflow;
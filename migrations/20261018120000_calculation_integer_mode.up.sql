@@ -0,0 +1,3 @@
+ALTER TABLE calculations
+    ADD COLUMN precision_int_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN precision_overflow TEXT NOT NULL DEFAULT '';
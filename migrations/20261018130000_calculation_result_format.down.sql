@@ -0,0 +1,6 @@
+ALTER TABLE calculations
+    DROP COLUMN format_digits,
+    DROP COLUMN format_decimals,
+    DROP COLUMN format_rounding,
+    DROP COLUMN format_notation,
+    DROP COLUMN format_grouping;
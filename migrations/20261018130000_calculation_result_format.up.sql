@@ -0,0 +1,6 @@
+ALTER TABLE calculations
+    ADD COLUMN format_digits INTEGER,
+    ADD COLUMN format_decimals INTEGER,
+    ADD COLUMN format_rounding TEXT NOT NULL DEFAULT '',
+    ADD COLUMN format_notation TEXT NOT NULL DEFAULT '',
+    ADD COLUMN format_grouping BOOLEAN NOT NULL DEFAULT FALSE;
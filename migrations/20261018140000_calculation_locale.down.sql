@@ -0,0 +1,3 @@
+ALTER TABLE calculations
+    DROP COLUMN canonical_expression,
+    DROP COLUMN locale;
@@ -0,0 +1,6 @@
+ALTER TABLE calculations
+    ADD COLUMN canonical_expression TEXT NOT NULL DEFAULT '',
+    ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+
+-- старые выражения записаны без локали
+UPDATE calculations SET canonical_expression = expression;
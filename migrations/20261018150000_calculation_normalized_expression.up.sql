@@ -0,0 +1,5 @@
+ALTER TABLE calculations
+    ADD COLUMN normalized_expression TEXT NOT NULL DEFAULT '';
+
+-- раньше выражения с такими знаками не сохранялись, нормализация их не меняет
+UPDATE calculations SET normalized_expression = expression;
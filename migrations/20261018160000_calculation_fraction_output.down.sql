@@ -0,0 +1,2 @@
+ALTER TABLE calculations
+    DROP COLUMN precision_output;
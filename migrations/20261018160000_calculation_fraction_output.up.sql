@@ -0,0 +1,5 @@
+ALTER TABLE calculations
+    ADD COLUMN precision_output TEXT NOT NULL DEFAULT '';
+
+-- точные результаты до этой миграции записаны десятичной дробью
+UPDATE calculations SET precision_output = 'decimal' WHERE precision_mode = 'exact';
@@ -0,0 +1,3 @@
+ALTER TABLE calculations
+    DROP COLUMN unit,
+    DROP COLUMN units;
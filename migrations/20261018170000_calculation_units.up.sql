@@ -0,0 +1,3 @@
+ALTER TABLE calculations
+    ADD COLUMN units BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN unit TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE calculations
+    DROP COLUMN rate_snapshot_id;
+
+DROP TABLE currency_rates;
+DROP TABLE rate_snapshots;
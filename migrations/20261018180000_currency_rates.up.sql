@@ -0,0 +1,18 @@
+CREATE TABLE rate_snapshots (
+    id TEXT PRIMARY KEY,
+    base TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_snapshots_created_at_idx ON rate_snapshots (created_at DESC);
+
+CREATE TABLE currency_rates (
+    snapshot_id TEXT NOT NULL REFERENCES rate_snapshots (id) ON DELETE CASCADE,
+    currency TEXT NOT NULL,
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (snapshot_id, currency)
+);
+
+-- таблица курсов, по которой посчитаны валюты выражения; NULL - валют не было
+ALTER TABLE calculations
+    ADD COLUMN rate_snapshot_id TEXT REFERENCES rate_snapshots (id);
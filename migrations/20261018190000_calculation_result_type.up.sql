@@ -0,0 +1,3 @@
+-- тип результата: number или boolean у сравнений и логических операций
+ALTER TABLE calculations
+    ADD COLUMN result_type TEXT NOT NULL DEFAULT 'number';
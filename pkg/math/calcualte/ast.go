@@ -0,0 +1,129 @@
+package calculable
+
+type node interface {
+	node()
+}
+
+// numberNode - литерал; value уже разобран для float64, text нужен точным режимам
+type numberNode struct {
+	text      string
+	value     float64
+	imaginary bool // 4i в режиме complex, value - коэффициент
+	radix     int  // основание литерала 0x, 0b, 0o; 0 у десятичных
+}
+
+type constantNode struct {
+	name  string
+	value float64
+}
+
+type variableNode struct {
+	name string
+}
+
+// span - место узла в исходном выражении, в рунах: [start, end)
+type span struct {
+	start, end int
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+	span
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+	span
+}
+
+type callNode struct {
+	name string
+	args []node
+	span
+}
+
+// unitNode - единица измерения в режиме units, см. WithUnits
+type unitNode struct {
+	name string
+	unit unit
+}
+
+// convertNode - перевод в единицу: "5 km in mi", target - выражение из единиц
+type convertNode struct {
+	op              string
+	operand, target node
+	span
+}
+
+// percentNode - процентная операция: 10%, 200 + 10%, 50% of 80, 100 -> 125; у "%" только left
+type percentNode struct {
+	op          string
+	left, right node
+	span
+}
+
+// logicNode - сравнение или логическая операция, результат - ValueBoolean; у "!" только left
+type logicNode struct {
+	op          string
+	left, right node
+	span
+}
+
+// conditionalNode - cond ? then : otherwise или if(cond, then, otherwise)
+type conditionalNode struct {
+	op                    string // "?" или "if"
+	cond, then, otherwise node
+	span
+}
+
+func (numberNode) node()      {}
+func (constantNode) node()    {}
+func (variableNode) node()    {}
+func (unaryNode) node()       {}
+func (binaryNode) node()      {}
+func (callNode) node()        {}
+func (unitNode) node()        {}
+func (convertNode) node()     {}
+func (percentNode) node()     {}
+func (logicNode) node()       {}
+func (conditionalNode) node() {}
+
+// walk - обход дерева в глубину, f вызывается для каждого узла до его потомков
+func walk(n node, f func(node)) {
+	f(n)
+	walkChildren(n, func(child node) { walk(child, f) })
+}
+
+// walkChildren - f для каждого непосредственного потомка n по порядку
+func walkChildren(n node, f func(node)) {
+	switch n := n.(type) {
+	case unaryNode:
+		f(n.operand)
+	case binaryNode:
+		f(n.left)
+		f(n.right)
+	case callNode:
+		for _, arg := range n.args {
+			f(arg)
+		}
+	case convertNode:
+		f(n.operand)
+		f(n.target)
+	case percentNode:
+		f(n.left)
+		if n.right != nil {
+			f(n.right)
+		}
+	case logicNode:
+		f(n.left)
+		if n.right != nil {
+			f(n.right)
+		}
+	case conditionalNode:
+		f(n.cond)
+		f(n.then)
+		f(n.otherwise)
+	}
+}
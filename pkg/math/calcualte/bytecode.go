@@ -0,0 +1,315 @@
+package calculable
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+type opcode uint8
+
+const (
+	opConst     opcode = iota // положить consts[arg]
+	opVar                     // положить значение переменной names[arg]
+	opUnary                   // применить unary[arg] к вершине стека
+	opBinary                  // снять два значения, положить binary[arg]([a, b])
+	opCall                    // снять calls[arg].argc значений, положить результат функции
+	opJump                    // перейти к code[arg]
+	opJumpFalse               // снять значение, перейти к code[arg], если это ноль
+	opJumpTrue                // снять значение, перейти к code[arg], если это не ноль
+)
+
+type instruction struct {
+	op  opcode
+	arg uint32
+}
+
+type callSite struct {
+	name string
+	fn   function
+	argc int
+}
+
+// bytecode - дерево выражения режима float, развёрнутое в постфиксную запись для стековой машины.
+// Операции и функции те же, что у floatArithmetic, поэтому результат совпадает с evaluate до бита.
+type bytecode struct {
+	code   []instruction
+	consts []float64
+	names  []string
+	unary  []func(args []float64) (float64, error)
+	binary []func(args []float64) (float64, error)
+	calls  []callSite
+}
+
+// compileBytecode - names задаёт номера переменных, в том же порядке run принимает values
+func compileBytecode(root node, names []string, r *Registry) *bytecode {
+	b := &bytecode{names: names}
+	b.emit(foldConstants(root, r), r)
+	return b
+}
+
+func (b *bytecode) emit(n node, r *Registry) {
+	switch n := n.(type) {
+	case numberNode:
+		b.pushConst(n.value)
+	case variableNode:
+		b.push(opVar, slices.Index(b.names, n.name))
+	case unaryNode:
+		b.emit(n.operand, r)
+		b.push(opUnary, len(b.unary))
+		b.unary = append(b.unary, r.unary[n.op])
+	case binaryNode:
+		b.emit(n.left, r)
+		b.emit(n.right, r)
+		b.push(opBinary, len(b.binary))
+		b.binary = append(b.binary, r.binary[n.op])
+	case callNode:
+		for _, arg := range n.args {
+			b.emit(arg, r)
+		}
+		b.push(opCall, len(b.calls))
+		b.calls = append(b.calls, callSite{name: n.name, fn: r.functions[n.name], argc: len(n.args)})
+	case logicNode:
+		b.emitLogic(n, r)
+	case conditionalNode:
+		b.emit(n.cond, r)
+		otherwise := b.push(opJumpFalse, 0)
+		b.emit(n.then, r)
+		end := b.push(opJump, 0)
+		b.label(otherwise)
+		b.emit(n.otherwise, r)
+		b.label(end)
+	case percentNode:
+		b.emit(n.left, r)
+		if n.right == nil {
+			b.push(opUnary, len(b.unary))
+			b.unary = append(b.unary, floatPercent(n.op))
+			return
+		}
+		b.emit(n.right, r)
+		b.push(opBinary, len(b.binary))
+		b.binary = append(b.binary, floatPercent(n.op))
+	}
+}
+
+// emitLogic - сравнения и ! - операции над вершиной стека, && и || - переходы,
+// правый операнд вычисляется, только если результат ещё не известен
+func (b *bytecode) emitLogic(n logicNode, r *Registry) {
+	b.emit(n.left, r)
+
+	switch n.op {
+	case notOperator:
+		b.push(opUnary, len(b.unary))
+		b.unary = append(b.unary, func(args []float64) (float64, error) {
+			return floatBool(args[0] == 0), nil
+		})
+	case "&&", "||":
+		// a && b: 0, если a или b - ноль, иначе 1; a || b - наоборот
+		jump, short, full := opJumpFalse, 0, 1
+		if n.op == "||" {
+			jump, short, full = opJumpTrue, 1, 0
+		}
+		first := b.push(jump, 0)
+		b.emit(n.right, r)
+		second := b.push(jump, 0)
+		b.pushConst(float64(full))
+		end := b.push(opJump, 0)
+		b.label(first)
+		b.label(second)
+		b.pushConst(float64(short))
+		b.label(end)
+	default:
+		// оператор сравнения известен при компиляции
+		holds, err := comparison(n.op)
+		b.emit(n.right, r)
+		b.push(opBinary, len(b.binary))
+		b.binary = append(b.binary, func(args []float64) (float64, error) {
+			if err != nil {
+				return 0, err
+			}
+			return floatBool(holds(cmp.Compare(args[0], args[1]))), nil
+		})
+	}
+}
+
+// floatBool - истина и ложь байткода, как boolean у floatArithmetic
+func floatBool(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// push - номер добавленной инструкции, для label
+func (b *bytecode) push(op opcode, arg int) int {
+	b.code = append(b.code, instruction{op: op, arg: uint32(arg)})
+	return len(b.code) - 1
+}
+
+func (b *bytecode) pushConst(value float64) {
+	b.push(opConst, len(b.consts))
+	b.consts = append(b.consts, value)
+}
+
+// label - переход jump ведёт на следующую инструкцию
+func (b *bytecode) label(jump int) {
+	b.code[jump].arg = uint32(len(b.code))
+}
+
+// стеки переиспользуются между вычислениями, чтобы run не выделял память
+var stackPool = sync.Pool{
+	New: func() any {
+		stack := make([]float64, 0, 32)
+		return &stack
+	},
+}
+
+// run - значения переменных берутся из values по номеру, а если values == nil - из vars по имени.
+// Встроенные операции быстрые, поэтому ctx проверяется до вычисления и перед вызовами функций.
+func (b *bytecode) run(ctx context.Context, vars Vars, values []float64) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	pooled := stackPool.Get().(*[]float64)
+	stack := (*pooled)[:0]
+	defer func() {
+		*pooled = stack[:0]
+		stackPool.Put(pooled)
+	}()
+
+	for pc := 0; pc < len(b.code); pc++ {
+		switch ins := b.code[pc]; ins.op {
+		case opConst:
+			stack = append(stack, b.consts[ins.arg])
+		case opVar:
+			if values != nil {
+				stack = append(stack, values[ins.arg])
+				break
+			}
+
+			name := b.names[ins.arg]
+			value, exists := vars[name]
+			if !exists {
+				return 0, &VariableError{Name: name, Err: ErrUndefinedVariable}
+			}
+			stack = append(stack, value)
+		// аргументы операторов передаются срезом стека, без копирования
+		case opUnary:
+			top := len(stack) - 1
+			res, err := finite(b.unary[ins.arg](stack[top:]))
+			if err != nil {
+				return 0, err
+			}
+			stack[top] = res
+		case opBinary:
+			top := len(stack) - 1
+			res, err := finite(b.binary[ins.arg](stack[top-1:]))
+			if err != nil {
+				return 0, err
+			}
+			stack = stack[:top]
+			stack[top-1] = res
+		case opCall:
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			call := b.calls[ins.arg]
+			args := stack[len(stack)-call.argc:]
+			res, err := finite(call.fn.apply(args))
+			if err != nil {
+				return 0, &FunctionError{Name: call.name, Err: err}
+			}
+			stack = append(stack[:len(stack)-call.argc], res)
+		// pc++ в конце цикла, поэтому переход на arg - 1
+		case opJump:
+			pc = int(ins.arg) - 1
+		case opJumpFalse, opJumpTrue:
+			top := len(stack) - 1
+			if (stack[top] != 0) == (ins.op == opJumpTrue) {
+				pc = int(ins.arg) - 1
+			}
+			stack = stack[:top]
+		}
+	}
+
+	return stack[0], nil
+}
+
+// foldConstants - поддеревья без переменных заменяются их значением.
+// Если вычисление поддерева даёт ошибку (1/0), оно остаётся как есть, чтобы ошибка возникла при run.
+func foldConstants(n node, r *Registry) node {
+	arithmetic := floatArithmetic{registry: r}
+
+	switch n := n.(type) {
+	case constantNode:
+		return numberNode{value: n.value}
+	case unaryNode:
+		operand := foldConstants(n.operand, r)
+		if num, ok := operand.(numberNode); ok {
+			if value, err := arithmetic.unary(n.op, num.value); err == nil {
+				return numberNode{value: value}
+			}
+		}
+		return unaryNode{op: n.op, operand: operand, span: n.span}
+	case binaryNode:
+		left, right := foldConstants(n.left, r), foldConstants(n.right, r)
+		x, xok := left.(numberNode)
+		y, yok := right.(numberNode)
+		if xok && yok {
+			if value, err := arithmetic.binary(n.op, x.value, y.value); err == nil {
+				return numberNode{value: value}
+			}
+		}
+		return binaryNode{op: n.op, left: left, right: right, span: n.span}
+	case callNode:
+		args := make([]node, len(n.args))
+		values := make([]float64, len(n.args))
+		allConst := true
+		for i, arg := range n.args {
+			args[i] = foldConstants(arg, r)
+			num, ok := args[i].(numberNode)
+			allConst = allConst && ok
+			values[i] = num.value
+		}
+		if allConst {
+			if value, err := arithmetic.call(n.name, values); err == nil {
+				return numberNode{value: value}
+			}
+		}
+		return callNode{name: n.name, args: args, span: n.span}
+	case logicNode:
+		folded := logicNode{op: n.op, left: foldConstants(n.left, r), span: n.span}
+		if n.right != nil {
+			folded.right = foldConstants(n.right, r)
+		}
+		return folded
+	case conditionalNode:
+		// условие известно - остаётся только выбранная ветвь
+		cond := foldConstants(n.cond, r)
+		if num, ok := cond.(numberNode); ok {
+			if num.value != 0 {
+				return foldConstants(n.then, r)
+			}
+			return foldConstants(n.otherwise, r)
+		}
+		return conditionalNode{op: n.op, cond: cond, then: foldConstants(n.then, r), otherwise: foldConstants(n.otherwise, r), span: n.span}
+	case percentNode:
+		folded := percentNode{op: n.op, left: foldConstants(n.left, r), span: n.span}
+		x, xok := folded.left.(numberNode)
+		y, yok := x, xok
+		if n.right != nil {
+			folded.right = foldConstants(n.right, r)
+			y, yok = folded.right.(numberNode)
+		}
+		if xok && yok {
+			if value, err := percent(arithmetic, n.op, x.value, y.value); err == nil {
+				return numberNode{value: value}
+			}
+		}
+		return folded
+	default:
+		return n
+	}
+}
@@ -1,29 +1,90 @@
 package calculable
 
 import (
+	"context"
 	"errors"
-	"strconv"
+	"math/big"
 )
 
 var (
 	ErrUnknownOperator      = errors.New("unknown operator")
+	ErrEmptyExpression      = errors.New("expression is empty")
 	ErrStartsWithOperator   = errors.New("expression must start with an operand")
 	ErrEndsWithOperator     = errors.New("expression must end with an operand")
 	ErrDoubleDot            = errors.New("invalid format: consecutive dots")
+	ErrUnexpectedDot        = errors.New("invalid number: unexpected decimal point")
+	ErrMissingDigits        = errors.New("invalid number: no digits")
+	ErrMissingExponent      = errors.New("invalid number: exponent has no digits")
+	ErrInvalidDigit         = errors.New("invalid number: digit is out of range for the base")
+	ErrMisplacedSeparator   = errors.New("invalid number: '_' must be between digits")
+	ErrMisplacedGroup       = errors.New("invalid number: thousands separator must be followed by three digits")
 	ErrConsecutiveOperators = errors.New("invalid format: consecutive operators")
+	ErrMissingOperator      = errors.New("invalid format: missing operator between operands")
 	ErrInvalidCharacter     = errors.New("unknown character in expression")
+	ErrUnclosedBracket      = errors.New("invalid format: unclosed bracket")
+	ErrUnopenedBracket      = errors.New("invalid format: closing bracket without opening one")
+	ErrEmptyBrackets        = errors.New("invalid format: empty brackets")
+	ErrDivisionByZero       = errors.New("math error: division by zero")
+	ErrModuloByZero         = errors.New("math error: modulo by zero")
+	ErrNegativeBasePower    = errors.New("math error: fractional power of a negative number")
+	ErrOverflow             = errors.New("math error: result is too large")
+	ErrNotANumber           = errors.New("math error: result is not a number")
+	ErrMissingCallBrackets  = errors.New("invalid format: function name without call brackets")
+	ErrUnexpectedComma      = errors.New("invalid format: comma outside of function arguments")
+	ErrEmptyArgument        = errors.New("invalid format: empty function argument")
+	ErrUnknownFunction      = errors.New("unknown function")
+	ErrArgumentCount        = errors.New("invalid number of function arguments")
+	ErrFunctionDomain       = errors.New("math error: argument out of function domain")
+	ErrUndefinedVariable    = errors.New("undefined variable")
+	ErrInvalidVariableName  = errors.New("invalid variable name")
+	ErrValueCount           = errors.New("number of values does not match program variables")
+	ErrUnknownPrecision     = errors.New("unknown precision mode")
+	ErrUnknownRounding      = errors.New("unknown rounding mode")
+	ErrInvalidScale         = errors.New("scale must be between 0 and 100")
+	ErrInexact              = errors.New("math error: operation has no exact result")
+	ErrExponentTooLarge     = errors.New("math error: exponent is too large for exact evaluation")
+	ErrUnknownIntType       = errors.New("unknown integer type")
+	ErrUnknownOverflow      = errors.New("unknown overflow mode")
+	ErrIntegerOverflow      = errors.New("math error: integer overflow")
+	ErrNotInteger           = errors.New("math error: value is not an integer")
+	ErrNegativeShift        = errors.New("math error: negative shift count")
+	ErrIntegerFunction      = errors.New("function is not available in integer mode")
+	ErrInvalidOperator      = errors.New("invalid operator definition")
+	ErrOperatorExists       = errors.New("operator is already registered")
+	ErrInvalidFunction      = errors.New("invalid function definition")
+	ErrFunctionExists       = errors.New("function name is already taken")
+	ErrTooComplex           = errors.New("expression is too complex")
+	ErrUnknownNotation      = errors.New("unknown number notation")
+	ErrInvalidDigits        = errors.New("significant digits must be between 1 and 100")
+	ErrFormatConflict       = errors.New("significant digits and decimal places are mutually exclusive")
+	ErrUnknownLocale        = errors.New("unknown locale")
+	ErrUnknownOutput        = errors.New("unknown output mode")
+	ErrFractionPrecision    = errors.New("fraction output requires exact precision")
+	ErrNotReal              = errors.New("math error: operation is defined only for real numbers")
+	ErrDimensionMismatch    = errors.New("math error: incompatible units")
+	ErrUnitsPrecision       = errors.New("units require float precision")
+	ErrExpectedUnit         = errors.New("invalid format: conversion target must be a unit")
+	ErrInvalidCurrency      = errors.New("currency code must be three uppercase latin letters")
+	ErrInvalidRate          = errors.New("currency rate must be a positive finite number")
+	ErrExpectedPercent      = errors.New("invalid format: of must follow a percentage")
+	ErrTypeMismatch         = errors.New("invalid format: boolean and number operands do not match")
+	ErrUnclosedConditional  = errors.New("invalid format: conditional without ':'")
 )
 
-var operations = map[rune]func(a, b float64) float64{
-	'+': func(a, b float64) float64 { return a + b },
-	'-': func(a, b float64) float64 { return a - b },
-	'*': func(a, b float64) float64 { return a * b },
-	'/': func(a, b float64) float64 { return a / b },
+// floatOperations - бинарные операции режима float, синтаксис описан в floatGrammar
+var floatOperations = map[string]func(a, b float64) (float64, error){
+	"+":  func(a, b float64) (float64, error) { return a + b, nil },
+	"-":  func(a, b float64) (float64, error) { return a - b, nil },
+	"*":  func(a, b float64) (float64, error) { return a * b, nil },
+	"/":  div,
+	"%":  mod,
+	"//": floorDiv,
+	"^":  pow,
 }
 
-type expression struct {
-	operands  []float64
-	operators []rune
+var unaryOperations = map[string]func(a float64) float64{
+	"+": func(a float64) float64 { return a },
+	"-": func(a float64) float64 { return -a },
 }
 
 type Calculable interface {
@@ -31,79 +92,37 @@ type Calculable interface {
 	SetResult(float64)
 }
 
-func CalculateExpression(c Calculable) error {
-	exp, err := parsing(c.GetExpression())
-	if err != nil {
-		return err
-	}
-
-	result := exp.operands[0]
-	for i, operator := range exp.operators {
-		operationFunc, exists := operations[operator]
-		if !exists {
-			return ErrUnknownOperator
-		}
-		result = operationFunc(result, exp.operands[i+1])
-	}
-
-	c.SetResult(result)
-	return nil
+// ExactCalculable - получатель точного результата в режиме PrecisionExact.
+// Если Calculable его не реализует, в SetResult придёт ближайший float64.
+type ExactCalculable interface {
+	Calculable
+	SetExactResult(*big.Rat)
 }
 
-// .
-func parsing(input string) (res expression, err error) {
-
-	// сначала операнд
-	if !('0' <= input[0] && input[0] <= '9') {
-		return res, ErrStartsWithOperator
-	}
-	// в конце также должен быть операнд
-	if !('0' <= input[len(input)-1] && input[len(input)-1] <= '9') {
-		return res, ErrEndsWithOperator
-	}
-
-	var buf = "" // буферная для res.operands[i]
-	// var buf float64
-
-	// парсим
-	for _, char := range input {
-		switch char {
-		case ' ':
-			continue
-		// операнд
-		case '.':
-			if len(buf) > 0 && buf[len(buf)-1] == '.' {
-				return res, ErrDoubleDot
-			}
-			fallthrough
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			buf += string(char)
-		// операции
-		case '+', '-', '*', '/':
-			if buf == "" || buf == "." {
-				return res, ErrConsecutiveOperators
-			}
+// NormalizedCalculable - получатель выражения после замены знаков из документов, см. Program.Normalized
+type NormalizedCalculable interface {
+	Calculable
+	SetNormalizedExpression(string)
+}
 
-			// преобразуем строку буфер в float64
-			newOperand, err := strconv.ParseFloat(buf, 64)
-			if err != nil {
-				return res, err
-			}
-			buf = ""
+// CanonicalCalculable - получатель записи выражения без локали, см. WithLocale и Program.Canonical
+type CanonicalCalculable interface {
+	Calculable
+	SetCanonicalExpression(string)
+}
 
-			res.operands = append(res.operands, newOperand)
-			res.operators = append(res.operators, char)
-		default:
-			return res, ErrInvalidCharacter
-		}
-	}
+// CalculateExpression - вычисляет выражение с подстановкой переменных из vars (может быть nil).
+// Для многократного вычисления одного выражения удобнее Compile.
+func CalculateExpression(c Calculable, vars Vars, opts ...Option) error {
+	return CalculateExpressionContext(context.Background(), c, vars, opts...)
+}
 
-	// последний операнд
-	newOperand, err := strconv.ParseFloat(buf, 64)
+// CalculateExpressionContext - CalculateExpression с отменой через ctx.
+// Размер выражения ограничен DefaultLimits или WithLimits, превышение - *LimitError.
+func CalculateExpressionContext(ctx context.Context, c Calculable, vars Vars, opts ...Option) error {
+	p, err := Compile(c.GetExpression(), opts...)
 	if err != nil {
-		return res, err
+		return err
 	}
-	res.operands = append(res.operands, newOperand)
-
-	return
+	return p.RunContext(ctx, c, vars)
 }
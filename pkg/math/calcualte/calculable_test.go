@@ -0,0 +1,1874 @@
+package calculable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testCalc struct {
+	expr   string
+	result float64
+}
+
+func (c testCalc) GetExpression() string {
+	return c.expr
+}
+
+func (c *testCalc) SetResult(res float64) {
+	c.result = res
+}
+
+type testExactCalc struct {
+	testCalc
+	exact *big.Rat
+}
+
+func (c *testExactCalc) SetExactResult(res *big.Rat) {
+	c.exact = res
+}
+
+type testIntCalc struct {
+	testCalc
+	integer *big.Int
+}
+
+func (c *testIntCalc) SetIntegerResult(res *big.Int) {
+	c.integer = res
+}
+
+type testComplexCalc struct {
+	testCalc
+	complex complex128
+}
+
+func (c *testComplexCalc) SetComplexResult(res complex128) {
+	c.complex = res
+}
+
+type testBoolCalc struct {
+	testCalc
+	boolean *bool
+}
+
+func (c *testBoolCalc) SetBooleanResult(res bool) {
+	c.boolean = &res
+}
+
+func TestCalculateExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    float64
+		wantErr error
+	}{
+		{name: "single operand", expr: "42", want: 42},
+		{name: "fractional operand", expr: "1.5+.5", want: 2},
+		{name: "multiplication before addition", expr: "2+3*4", want: 14},
+		{name: "multiplication first", expr: "2*3+4", want: 10},
+		{name: "division before subtraction", expr: "10-6/2", want: 7},
+		{name: "left associative subtraction", expr: "10-4-3", want: 3},
+		{name: "left associative division", expr: "8/4/2", want: 1},
+		{name: "mixed precedence chain", expr: "1+2*3-4/2", want: 5},
+		{name: "spaces between tokens", expr: " 2 + 3 * 4 ", want: 14},
+		{name: "brackets override precedence", expr: "(2+3)*4", want: 20},
+		{name: "brackets on the right", expr: "10-(4-3)", want: 9},
+		{name: "nested brackets", expr: "((1+2)*(3+4))/(7)", want: 3},
+		{name: "deeply nested brackets", expr: "(((((5)))))", want: 5},
+		{name: "negative literal", expr: "-5", want: -5},
+		{name: "unary plus", expr: "+5", want: 5},
+		{name: "unary minus after operator", expr: "2*-3", want: -6},
+		{name: "unary plus after operator", expr: "1++2", want: 3},
+		{name: "unary minus after bracket", expr: "(-2+5)*2", want: 6},
+		{name: "unary minus before bracket", expr: "-(2+3)", want: -5},
+		{name: "double negation", expr: "--2", want: 2},
+		{name: "unary minus binds tighter than multiplication", expr: "-2*3+1", want: -5},
+		{name: "power", expr: "2^10", want: 1024},
+		{name: "power is right associative", expr: "2^3^2", want: 512},
+		{name: "power before multiplication", expr: "3*2^2", want: 12},
+		{name: "power binds tighter than unary minus", expr: "-2^2", want: -4},
+		{name: "negative exponent", expr: "2^-1", want: 0.5},
+		{name: "negative base integer exponent", expr: "(-2)^3", want: -8},
+		{name: "fractional exponent", expr: "16^0.5", want: 4},
+		{name: "modulo", expr: "7%3", want: 1},
+		{name: "modulo keeps dividend sign", expr: "-7%3", want: -1},
+		{name: "modulo same precedence as multiplication", expr: "1+10%4*2", want: 5},
+		{name: "floor division", expr: "7//2", want: 3},
+		{name: "floor division rounds down", expr: "-7//2", want: -4},
+		{name: "floor division left associative", expr: "100//7//2", want: 7},
+		{name: "square root", expr: "sqrt(16)", want: 4},
+		{name: "cube root of negative", expr: "cbrt(-27)", want: -3},
+		{name: "function in expression", expr: "2*sqrt(9)+1", want: 7},
+		{name: "nested functions", expr: "abs(floor(-2.5))", want: 3},
+		{name: "function of expression", expr: "sqrt(3^2+4^2)", want: 5},
+		{name: "unary minus before function", expr: "-abs(-2)^2", want: -4},
+		{name: "sine", expr: "sin(0)", want: 0},
+		{name: "arc cosine", expr: "acos(-1)", want: math.Pi},
+		{name: "arc tangent of two args", expr: "atan2(1, 1)*4", want: math.Pi},
+		{name: "natural logarithm", expr: "ln(exp(2))", want: 2},
+		{name: "decimal logarithm", expr: "log(1000)", want: 3},
+		{name: "logarithm with base", expr: "log(8, 2)", want: 3},
+		{name: "binary logarithm", expr: "log2(1024)", want: 10},
+		{name: "ceil", expr: "ceil(1.2)", want: 2},
+		{name: "trunc", expr: "trunc(-1.7)", want: -1},
+		{name: "round half away from zero", expr: "round(-2.5)", want: -3},
+		{name: "round to digits", expr: "round(3.14159, 2)", want: 3.14},
+		{name: "round to tens", expr: "round(1234, -1)", want: 1230},
+		{name: "round large value", expr: "round(1e300, 100)", want: 1e300},
+		{name: "variadic min", expr: "min(3, 1, 2)", want: 1},
+		{name: "variadic max", expr: "max(1, 2, 3)", want: 3},
+		{name: "max of one", expr: "max(7)", want: 7},
+		{name: "arguments with brackets", expr: "max((1+2)*2, min(10, 20))", want: 10},
+		{name: "pi constant", expr: "cos(pi)", want: -1},
+		{name: "e constant", expr: "ln(e^2)", want: 2},
+		{name: "tau constant", expr: "tau/pi", want: 2},
+		{name: "scientific notation", expr: "1.5e-3*2E+3", want: 3},
+		{name: "exponent without sign", expr: "2e2", want: 200},
+		{name: "trailing dot with exponent", expr: "5.e1", want: 50},
+		{name: "leading dot with exponent", expr: ".5e1", want: 5},
+		{name: "underflow to zero", expr: "1e-400", want: 0},
+		{name: "hexadecimal", expr: "0xFF+0Xa", want: 265},
+		{name: "binary", expr: "0b1010", want: 10},
+		{name: "octal", expr: "0o17", want: 15},
+		{name: "digit separators", expr: "1_000_000.000_5+0xFF_FF", want: 1065535.0005},
+		{name: "hexadecimal e is a digit", expr: "0x1e5", want: 485},
+		{name: "leading zeros are decimal", expr: "010", want: 10},
+		{name: "empty", expr: "  ", wantErr: ErrEmptyExpression},
+		{name: "second decimal point", expr: "1.2.3", wantErr: ErrUnexpectedDot},
+		{name: "decimal point in exponent", expr: "1e5.5", wantErr: ErrUnexpectedDot},
+		{name: "fractional hexadecimal", expr: "0x1.8", wantErr: ErrUnexpectedDot},
+		{name: "lone dot", expr: "1+.", wantErr: ErrMissingDigits},
+		{name: "prefix without digits", expr: "0x+1", wantErr: ErrMissingDigits},
+		{name: "exponent without digits", expr: "1e+", wantErr: ErrMissingExponent},
+		{name: "exponent followed by letter", expr: "2ex", wantErr: ErrMissingExponent},
+		{name: "binary digit out of range", expr: "0b102", wantErr: ErrInvalidDigit},
+		{name: "octal digit out of range", expr: "0o8", wantErr: ErrInvalidDigit},
+		{name: "hexadecimal digit out of range", expr: "0xFG", wantErr: ErrInvalidDigit},
+		{name: "trailing separator", expr: "1_", wantErr: ErrMisplacedSeparator},
+		{name: "double separator", expr: "1__0", wantErr: ErrMisplacedSeparator},
+		{name: "separator before dot", expr: "1_.5", wantErr: ErrMisplacedSeparator},
+		{name: "separator after prefix", expr: "0x_FF", wantErr: ErrMisplacedSeparator},
+		{name: "exponent overflow", expr: "1e400", wantErr: ErrOverflow},
+		{name: "hexadecimal overflow", expr: "0x1" + strings.Repeat("0", 300), wantErr: ErrOverflow},
+		{name: "starts with operator", expr: "*2", wantErr: ErrStartsWithOperator},
+		{name: "ends with operator", expr: "2+", wantErr: ErrEndsWithOperator},
+		{name: "only unary operator", expr: "-", wantErr: ErrEndsWithOperator},
+		{name: "consecutive operators", expr: "1+*2", wantErr: ErrConsecutiveOperators},
+		{name: "consecutive dots", expr: "1..2", wantErr: ErrDoubleDot},
+		{name: "missing operator", expr: "1 2", wantErr: ErrMissingOperator},
+		{name: "invalid character", expr: "1+$", wantErr: ErrInvalidCharacter},
+		{name: "division by zero", expr: "1/0", wantErr: ErrDivisionByZero},
+		{name: "division by computed zero", expr: "1/(0.5-1/2)", wantErr: ErrDivisionByZero},
+		{name: "zero to negative power", expr: "0^-2", wantErr: ErrDivisionByZero},
+		{name: "multiplication overflow", expr: "2^1000*2^1000", wantErr: ErrOverflow},
+		{name: "power overflow", expr: "10^400", wantErr: ErrOverflow},
+		{name: "negative overflow", expr: "-(9^400)", wantErr: ErrOverflow},
+		{name: "function overflow", expr: "exp(1000)", wantErr: ErrOverflow},
+		{name: "literal overflow", expr: "1" + strings.Repeat("0", 400), wantErr: ErrOverflow},
+		{name: "modulo by zero", expr: "5%0", wantErr: ErrModuloByZero},
+		{name: "floor division by zero", expr: "5//(2-2)", wantErr: ErrDivisionByZero},
+		{name: "fractional power of negative", expr: "(-8)^(1/3)", wantErr: ErrNegativeBasePower},
+		{name: "triple slash", expr: "6///2", wantErr: ErrConsecutiveOperators},
+		{name: "unknown function", expr: "foo(1)", wantErr: ErrUnknownFunction},
+		{name: "constant is not a function", expr: "pi(1)", wantErr: ErrUnknownFunction},
+		{name: "undefined variable", expr: "2*x", wantErr: ErrUndefinedVariable},
+		{name: "function without call", expr: "sqrt+1", wantErr: ErrMissingCallBrackets},
+		{name: "too many arguments", expr: "sqrt(4, 2)", wantErr: ErrArgumentCount},
+		{name: "no arguments", expr: "max()", wantErr: ErrArgumentCount},
+		{name: "empty argument", expr: "max(1,,2)", wantErr: ErrEmptyArgument},
+		{name: "trailing comma", expr: "max(1,)", wantErr: ErrEmptyArgument},
+		{name: "comma outside of call", expr: "1,2", wantErr: ErrUnexpectedComma},
+		{name: "comma inside brackets", expr: "(1,2)", wantErr: ErrUnexpectedComma},
+		{name: "square root of negative", expr: "sqrt(-1)", wantErr: ErrFunctionDomain},
+		{name: "logarithm of zero", expr: "ln(0)", wantErr: ErrFunctionDomain},
+		{name: "logarithm base one", expr: "log(10, 1)", wantErr: ErrFunctionDomain},
+		{name: "arc sine out of range", expr: "asin(2)", wantErr: ErrFunctionDomain},
+		{name: "round to fractional digits", expr: "round(1, 0.5)", wantErr: ErrFunctionDomain},
+		{name: "round to too many digits", expr: "round(1.5, 400)", wantErr: ErrFunctionDomain},
+		{name: "round to too many tens", expr: "round(1.5, -400)", wantErr: ErrFunctionDomain},
+		{name: "unclosed bracket", expr: "(1+2", wantErr: ErrUnclosedBracket},
+		{name: "closing bracket without opening", expr: "1+2)", wantErr: ErrUnopenedBracket},
+		{name: "misordered brackets", expr: ")1+2(", wantErr: ErrUnopenedBracket},
+		{name: "empty brackets", expr: "1+()", wantErr: ErrEmptyBrackets},
+		{name: "group starts with operator", expr: "(*2)", wantErr: ErrStartsWithOperator},
+		{name: "binary operator after unary", expr: "2*-*3", wantErr: ErrConsecutiveOperators},
+		{name: "group ends with operator", expr: "(2+)", wantErr: ErrEndsWithOperator},
+		{name: "missing operator before group", expr: "2(3)", wantErr: ErrMissingOperator},
+		{name: "missing operator after group", expr: "(2)3", wantErr: ErrMissingOperator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testCalc{expr: tt.expr}
+			err := CalculateExpression(c, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+				return
+			}
+			if err == nil && math.Abs(c.result-tt.want) > 1e-12 {
+				t.Errorf("CalculateExpression(%q) = %v, want %v", tt.expr, c.result, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctionError(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{name: "arity", expr: "sqrt(1, 2)", want: "invalid number of function arguments: sqrt expects 1, got 2"},
+		{name: "variadic arity", expr: "min()", want: "invalid number of function arguments: min expects at least 1, got 0"},
+		{name: "optional argument arity", expr: "log(1, 2, 3)", want: "invalid number of function arguments: log expects 1 to 2, got 3"},
+		{name: "domain", expr: "sqrt(-4)", want: "math error: argument out of function domain: sqrt"},
+		{name: "unknown", expr: "sqr(4)", want: "unknown function: sqr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CalculateExpression(&testCalc{expr: tt.expr}, nil)
+
+			var fnErr *FunctionError
+			if !errors.As(err, &fnErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, want *FunctionError", tt.expr, err)
+			}
+			if fnErr.Error() != tt.want {
+				t.Errorf("FunctionError.Error() = %q, want %q", fnErr.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	operand := []string{"number", "identifier", "(", "!", "+", "-"}
+
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr error
+		want    ParseError
+		caret   string
+	}{
+		{
+			name:    "consecutive operators",
+			expr:    "1 + * 2",
+			wantErr: ErrConsecutiveOperators,
+			want:    ParseError{Offset: 4, ByteOffset: 4, Token: "*", Expected: operand},
+			caret:   "1 + * 2\n    ^",
+		},
+		{
+			name:    "missing operator",
+			expr:    "(1 2)",
+			wantErr: ErrMissingOperator,
+			want:    ParseError{Offset: 3, ByteOffset: 3, Token: "2", Expected: []string{"operator", ")"}},
+			caret:   "(1 2)\n   ^",
+		},
+		{
+			name:    "ends with operator",
+			expr:    "2+",
+			wantErr: ErrEndsWithOperator,
+			want:    ParseError{Offset: 2, ByteOffset: 2, Token: "", Expected: operand},
+			caret:   "2+\n  ^",
+		},
+		{
+			name:    "unclosed bracket points to opening one",
+			expr:    "(1+(2",
+			wantErr: ErrUnclosedBracket,
+			want:    ParseError{Offset: 3, ByteOffset: 3, Token: "(", Expected: []string{")"}},
+			caret:   "(1+(2\n   ^",
+		},
+		{
+			name:    "multibyte runes before error",
+			expr:    "число\t+ $",
+			wantErr: ErrInvalidCharacter,
+			want:    ParseError{Offset: 8, ByteOffset: 13, Token: "$"},
+			caret:   "число\t+ $\n     \t  ^",
+		},
+		{
+			name:    "function error keeps its type",
+			expr:    "1 + sqrt(1, 2)",
+			wantErr: ErrArgumentCount,
+			want:    ParseError{Offset: 4, ByteOffset: 4, Token: "sqrt"},
+			caret:   "1 + sqrt(1, 2)\n    ^",
+		},
+		{
+			name:    "malformed literal points to offending digit",
+			expr:    "2 * 0b1012",
+			wantErr: ErrInvalidDigit,
+			want:    ParseError{Offset: 9, ByteOffset: 9, Token: "2", Expected: []string{"digit"}},
+			caret:   "2 * 0b1012\n         ^",
+		},
+		{
+			name:    "missing exponent at end",
+			expr:    "1.5e-",
+			wantErr: ErrMissingExponent,
+			want:    ParseError{Offset: 5, ByteOffset: 5, Token: "", Expected: []string{"digit"}},
+			caret:   "1.5e-\n     ^",
+		},
+		{
+			name:    "comma outside of call",
+			expr:    "1,2",
+			wantErr: ErrUnexpectedComma,
+			want:    ParseError{Offset: 1, ByteOffset: 1, Token: ",", Expected: []string{"operator", "end of expression"}},
+			caret:   "1,2\n ^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CalculateExpression(&testCalc{expr: tt.expr}, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, want %v", tt.expr, err, tt.wantErr)
+			}
+
+			var pErr *ParseError
+			if !errors.As(err, &pErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, want *ParseError", tt.expr, err)
+			}
+			if pErr.Expression != tt.expr {
+				t.Errorf("ParseError.Expression = %q, want %q", pErr.Expression, tt.expr)
+			}
+			got := ParseError{Offset: pErr.Offset, ByteOffset: pErr.ByteOffset, Token: pErr.Token, Expected: pErr.Expected}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseError = %+v, want %+v", got, tt.want)
+			}
+			if pErr.Caret() != tt.caret {
+				t.Errorf("ParseError.Caret() = %q, want %q", pErr.Caret(), tt.caret)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionWithVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		want    float64
+		wantErr error
+	}{
+		{name: "circle area", expr: "pi * r^2", vars: Vars{"r": 2}, want: 4 * math.Pi},
+		{name: "several variables", expr: "price*qty - discount", vars: Vars{"price": 2.5, "qty": 4, "discount": 1}, want: 9},
+		{name: "variable in function", expr: "max(a, b, 0)", vars: Vars{"a": -1, "b": -2}, want: 0},
+		{name: "unicode and underscores", expr: "ставка_1 * 2", vars: Vars{"ставка_1": 3}, want: 6},
+		{name: "unused variables are fine", expr: "1+1", vars: Vars{"x": 1}, want: 2},
+		{name: "undefined variable", expr: "x + y", vars: Vars{"x": 1}, wantErr: ErrUndefinedVariable},
+		{name: "shadowing constant", expr: "pi", vars: Vars{"pi": 3}, wantErr: ErrInvalidVariableName},
+		{name: "shadowing function", expr: "1", vars: Vars{"sqrt": 3}, wantErr: ErrInvalidVariableName},
+		{name: "not an identifier", expr: "1", vars: Vars{"1x": 3}, wantErr: ErrInvalidVariableName},
+		{name: "empty name", expr: "1", vars: Vars{"": 3}, wantErr: ErrInvalidVariableName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testCalc{expr: tt.expr}
+			err := CalculateExpression(c, tt.vars)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+				return
+			}
+			if err == nil && math.Abs(c.result-tt.want) > 1e-12 {
+				t.Errorf("CalculateExpression(%q) = %v, want %v", tt.expr, c.result, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionExact(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		want    string
+		wantErr error
+	}{
+		{name: "no binary float error", expr: "0.1+0.2", want: "0.3"},
+		{name: "precedence and brackets", expr: "(1.1+2.2)*3", want: "9.9"},
+		{name: "repeating fraction", expr: "1/3", want: "0.33333333333333333333"},
+		{name: "fraction is kept exact", expr: "1/3*3", want: "1"},
+		{name: "negative modulo", expr: "-7.5%2", want: "-1.5"},
+		{name: "floor division", expr: "-7//2", want: "-4"},
+		{name: "integer power", expr: "1.1^2", want: "1.21"},
+		{name: "negative power", expr: "2^-3", want: "0.125"},
+		{name: "large power", expr: "2^100", want: "1267650600228229401496703205376"},
+		{name: "exact square root", expr: "sqrt(0.25)", want: "0.5"},
+		{name: "round half away from zero", expr: "round(-2.345, 2)", want: "-2.35"},
+		{name: "min and max", expr: "max(0.1, 0.2) - min(0.3, 0.4)", want: "-0.1"},
+		{name: "floor and ceil", expr: "floor(-1.5) + ceil(1.2) + abs(-1) + trunc(-0.9)", want: "1"},
+		{name: "variables use shortest decimal", expr: "price * qty", vars: Vars{"price": 0.1, "qty": 3}, want: "0.3"},
+		{name: "scientific notation", expr: "1.5e-3+0.1e1", want: "1.0015"},
+		{name: "radix literals", expr: "0xFF + 0b1_0000_0000 + 0o7", want: "518"},
+		{name: "small exponent is exact", expr: "1e-30*1e30", want: "1"},
+		{name: "division by zero", expr: "1/(2-2)", wantErr: ErrDivisionByZero},
+		{name: "literal exponent too large", expr: "1e-100000", wantErr: ErrExponentTooLarge},
+		{name: "modulo by zero", expr: "1%0", wantErr: ErrModuloByZero},
+		{name: "zero to negative power", expr: "0^-1", wantErr: ErrDivisionByZero},
+		{name: "fractional power", expr: "2^0.5", wantErr: ErrInexact},
+		{name: "fractional power of negative", expr: "(-2)^0.5", wantErr: ErrNegativeBasePower},
+		{name: "irrational square root", expr: "sqrt(2)", wantErr: ErrInexact},
+		{name: "irrational constant", expr: "2*pi", wantErr: ErrInexact},
+		{name: "transcendental function", expr: "sin(1)", wantErr: ErrInexact},
+		{name: "huge exponent", expr: "10^100000", wantErr: ErrExponentTooLarge},
+		{name: "huge product of allowed powers", expr: strings.Repeat("10^16000*", 50) + "1", wantErr: ErrTooComplex},
+		{name: "square root of negative", expr: "sqrt(-4)", wantErr: ErrFunctionDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testExactCalc{testCalc: testCalc{expr: tt.expr}}
+			err := CalculateExpression(c, tt.vars, WithPrecision(PrecisionExact))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := FormatDecimal(c.exact, AutoScale, RoundHalfEven); got != tt.want {
+				t.Errorf("CalculateExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionExactFallback(t *testing.T) {
+	c := &testCalc{expr: "0.1+0.2"}
+	if err := CalculateExpression(c, nil, WithPrecision(PrecisionExact)); err != nil {
+		t.Fatalf("CalculateExpression() error = %v", err)
+	}
+	if c.result != 0.3 {
+		t.Errorf("CalculateExpression() = %v, want 0.3", c.result)
+	}
+
+	huge := &testCalc{expr: "10^400"}
+	if err := CalculateExpression(huge, nil, WithPrecision(PrecisionExact)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("CalculateExpression() error = %v, want %v", err, ErrOverflow)
+	}
+
+	if err := CalculateExpression(c, nil, WithPrecision("decimal")); !errors.Is(err, ErrUnknownPrecision) {
+		t.Errorf("CalculateExpression() error = %v, want %v", err, ErrUnknownPrecision)
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		scale int
+		mode  RoundingMode
+		want  string
+	}{
+		{name: "pads with zeros", value: "0.3", scale: 2, mode: RoundHalfEven, want: "0.30"},
+		{name: "zero scale", value: "2.5", scale: 0, mode: RoundHalfEven, want: "2"},
+		{name: "half even rounds to even", value: "0.125", scale: 2, mode: RoundHalfEven, want: "0.12"},
+		{name: "half even rounds up above half", value: "0.1251", scale: 2, mode: RoundHalfEven, want: "0.13"},
+		{name: "half up", value: "0.125", scale: 2, mode: RoundHalfUp, want: "0.13"},
+		{name: "half up negative", value: "-0.125", scale: 2, mode: RoundHalfUp, want: "-0.13"},
+		{name: "half down", value: "0.125", scale: 2, mode: RoundHalfDown, want: "0.12"},
+		{name: "up", value: "0.121", scale: 2, mode: RoundUp, want: "0.13"},
+		{name: "truncate", value: "-0.129", scale: 2, mode: RoundTruncate, want: "-0.12"},
+		{name: "ceiling negative", value: "-0.129", scale: 2, mode: RoundCeiling, want: "-0.12"},
+		{name: "floor negative", value: "-0.121", scale: 2, mode: RoundFloor, want: "-0.13"},
+		{name: "no negative zero", value: "-0.001", scale: 2, mode: RoundHalfEven, want: "0.00"},
+		{name: "leading zeros", value: "0.0001", scale: 4, mode: RoundHalfEven, want: "0.0001"},
+		{name: "integer", value: "-1234", scale: 1, mode: RoundHalfEven, want: "-1234.0"},
+		{name: "auto trims zeros", value: "2.50", scale: AutoScale, mode: RoundHalfEven, want: "2.5"},
+		{name: "auto integer", value: "7", scale: AutoScale, mode: RoundHalfEven, want: "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := new(big.Rat).SetString(tt.value)
+			if got := FormatDecimal(r, tt.scale, tt.mode); got != tt.want {
+				t.Errorf("FormatDecimal(%s, %d, %s) = %v, want %v", tt.value, tt.scale, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionInteger(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		intType  IntType
+		overflow Overflow
+		vars     Vars
+		want     string
+		wantErr  error
+	}{
+		{name: "arithmetic", expr: "7/2 + 7%3 + -7//2", want: "0"},
+		{name: "bitwise", expr: "0xF0 | 0x0F & 0x3C ^ 0b1", want: "253"},
+		{name: "caret is xor", expr: "6^3", want: "5"},
+		{name: "shift below addition", expr: "1 << 2 + 1", want: "8"},
+		{name: "shift right is arithmetic", expr: "-16 >> 2", want: "-4"},
+		{name: "not signed", expr: "~0", want: "-1"},
+		{name: "not unsigned", expr: "~0", intType: Uint8, want: "255"},
+		{name: "integer exponent literal", expr: "1e3 + 1", want: "1001"},
+		{name: "variables", expr: "flags & mask", vars: Vars{"flags": 13, "mask": 4}, want: "4"},
+		{name: "functions", expr: "abs(-5) + max(1, 2, 3) - min(4, -4)", want: "12"},
+		{name: "uint64 keeps all bits", expr: "0xFFFFFFFFFFFFFFFF - 1", intType: Uint64, want: "18446744073709551614"},
+		{name: "wrap", expr: "127 + 1", intType: Int8, want: "-128"},
+		{name: "wrap unsigned below zero", expr: "0 - 1", intType: Uint16, want: "65535"},
+		{name: "wrap literal", expr: "0xFF", intType: Int8, want: "-1"},
+		{name: "radix literal is a bit pattern", expr: "0xFF & 0x0F", intType: Int8, overflow: OverflowError, want: "15"},
+		{name: "binary literal is a bit pattern", expr: "0b1000_0000", intType: Int8, overflow: OverflowError, want: "-128"},
+		{name: "radix literal wider than type", expr: "0x100", intType: Int8, overflow: OverflowError, wantErr: ErrIntegerOverflow},
+		{name: "decimal literal is range-checked", expr: "255", intType: Int8, overflow: OverflowError, wantErr: ErrIntegerOverflow},
+		{name: "wrap min divided by minus one", expr: "-128 / -1", intType: Int8, want: "-128"},
+		{name: "wrap long shift", expr: "1 << 100", intType: Int32, want: "0"},
+		{name: "saturate", expr: "100 * 100", intType: Int8, overflow: OverflowSaturate, want: "127"},
+		{name: "saturate negative", expr: "-100 - 100", intType: Int8, overflow: OverflowSaturate, want: "-128"},
+		{name: "saturate unsigned", expr: "1 - 2", intType: Uint32, overflow: OverflowSaturate, want: "0"},
+		{name: "in range with error mode", expr: "2147483647", intType: Int32, overflow: OverflowError, want: "2147483647"},
+		{name: "overflow error", expr: "2147483647 + 1", intType: Int32, overflow: OverflowError, wantErr: ErrIntegerOverflow},
+		{name: "abs of min overflows", expr: "abs(-128)", intType: Int8, overflow: OverflowError, wantErr: ErrIntegerOverflow},
+		{name: "fractional literal", expr: "1.5 + 1", wantErr: ErrNotInteger},
+		{name: "fractional variable", expr: "x", vars: Vars{"x": 0.5}, wantErr: ErrNotInteger},
+		{name: "constant", expr: "pi", wantErr: ErrNotInteger},
+		{name: "division by zero", expr: "1/0", wantErr: ErrDivisionByZero},
+		{name: "modulo by zero", expr: "1%0", wantErr: ErrModuloByZero},
+		{name: "negative shift", expr: "1 << -1", wantErr: ErrNegativeShift},
+		{name: "float function", expr: "sqrt(16)", wantErr: ErrIntegerFunction},
+		{name: "no power operator", expr: "2 ** 3", wantErr: ErrConsecutiveOperators},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testIntCalc{testCalc: testCalc{expr: tt.expr}}
+			opts := []Option{WithPrecision(PrecisionInteger), WithOverflow(tt.overflow)}
+			if tt.intType != "" {
+				opts = append(opts, WithIntegerType(tt.intType))
+			}
+
+			err := CalculateExpression(c, tt.vars, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := c.integer.String(); got != tt.want {
+				t.Errorf("CalculateExpression(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegerGrammarIsModeSpecific(t *testing.T) {
+	err := CalculateExpression(&testCalc{expr: "6 & 3"}, nil)
+	if !errors.Is(err, ErrInvalidCharacter) {
+		t.Errorf("CalculateExpression(%q) error = %v, want %v", "6 & 3", err, ErrInvalidCharacter)
+	}
+
+	c := &testCalc{expr: "6 ^ 3"}
+	if err := CalculateExpression(c, nil, WithPrecision(PrecisionInteger)); err != nil || c.result != 5 {
+		t.Errorf("CalculateExpression(%q) in integer mode = %v, %v, want 5 via SetResult", c.expr, c.result, err)
+	}
+}
+
+func TestCalculateExpressionComplex(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		want    string
+		wantErr error
+	}{
+		{name: "product", expr: "(3+4i) * (1-2i)", want: "11-2i"},
+		{name: "division", expr: "(11-2i) / (1-2i)", want: "3+4i"},
+		{name: "unit", expr: "i", want: "1i"},
+		{name: "unit squared", expr: "i^2", want: "-1"},
+		{name: "unit times literal", expr: "2*i + 0.5i", want: "2.5i"},
+		{name: "sqrt of negative", expr: "sqrt(-4)", want: "2i"},
+		{name: "cbrt of negative is real", expr: "cbrt(-8)", want: "-2"},
+		{name: "abs", expr: "abs(3+4i)", want: "5"},
+		{name: "parts", expr: "re(3+4i) + im(3+4i)", want: "7"},
+		{name: "conjugate", expr: "conj(3+4i)", want: "3-4i"},
+		{name: "arg", expr: "arg(-1)", want: "3.141592653589793"},
+		{name: "euler identity", expr: "exp(i*pi) + 1", want: "0.00000000000000012246467991473515i"},
+		{name: "ln of negative", expr: "ln(-1)", want: "3.141592653589793i"},
+		{name: "negative integer power", expr: "(1+i)^-2", want: "-0.5i"},
+		{name: "real power stays real", expr: "2^0.5", want: "1.4142135623730951"},
+		{name: "fractional power of negative", expr: "(-4)^0.5", want: "0.00000000000000012246467991473515+2i"},
+		{name: "real function", expr: "floor(2.5) + max(1, 3)", want: "5"},
+		{name: "variables", expr: "x + y*i", vars: Vars{"x": 1, "y": 2}, want: "1+2i"},
+		{name: "division by zero", expr: "1i / 0", wantErr: ErrDivisionByZero},
+		{name: "zero to negative power", expr: "0^-1", wantErr: ErrDivisionByZero},
+		{name: "log of zero", expr: "ln(0)", wantErr: ErrFunctionDomain},
+		{name: "modulo of complex", expr: "(1+i) % 2", wantErr: ErrNotReal},
+		{name: "real function of complex", expr: "floor(i)", wantErr: ErrNotReal},
+		{name: "unit is not a variable", expr: "i", vars: Vars{"i": 1}, wantErr: ErrInvalidVariableName},
+		{name: "literal followed by name", expr: "2in", wantErr: ErrMissingOperator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testComplexCalc{testCalc: testCalc{expr: tt.expr}}
+			err := CalculateExpression(c, tt.vars, WithPrecision(PrecisionComplex))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := FormatComplex(c.complex); got != tt.want {
+				t.Errorf("CalculateExpression(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplexGrammarIsModeSpecific(t *testing.T) {
+	err := CalculateExpression(&testCalc{expr: "2i"}, nil)
+	if !errors.Is(err, ErrMissingOperator) {
+		t.Errorf("CalculateExpression(%q) error = %v, want %v", "2i", err, ErrMissingOperator)
+	}
+
+	// без ComplexCalculable мнимый результат - ошибка, вещественный - SetResult
+	c := &testCalc{expr: "sqrt(-4)"}
+	if err := CalculateExpression(c, nil, WithPrecision(PrecisionComplex)); !errors.Is(err, ErrNotReal) {
+		t.Errorf("CalculateExpression(%q) in complex mode error = %v, want %v", c.expr, err, ErrNotReal)
+	}
+	c = &testCalc{expr: "abs(3+4i)"}
+	if err := CalculateExpression(c, nil, WithPrecision(PrecisionComplex)); err != nil || c.result != 5 {
+		t.Errorf("CalculateExpression(%q) in complex mode = %v, %v, want 5 via SetResult", c.expr, c.result, err)
+	}
+}
+
+type testQuantityCalc struct {
+	testCalc
+	unit string
+}
+
+func (c *testQuantityCalc) SetQuantityResult(value float64, unit string) {
+	c.result, c.unit = value, unit
+}
+
+func TestCalculateExpressionUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		want    string
+		wantErr error
+	}{
+		{name: "sum in left unit", expr: "5 km + 300 m", want: "5.3 km"},
+		{name: "conversion", expr: "5 km + 300 m in mi", want: "3.29326731885787 mi"},
+		{name: "to", expr: "100 km/h to m/s", want: "27.77777777777778 m/s"},
+		{name: "product", expr: "9.81 m/s^2 * 70 kg", want: "686.7 m*kg/s^2"},
+		{name: "product to newton", expr: "9.81 m/s^2 * 70 kg in N", want: "686.7 N"},
+		{name: "unit binds tighter than division", expr: "1 / 2 s", want: "0.5 1/s"},
+		{name: "area", expr: "2 m * 3 m", want: "6 m^2"},
+		{name: "power", expr: "(3 m)^2 in ft^2", want: "96.8751937503875 ft^2"},
+		{name: "sqrt of area", expr: "sqrt(16 m^2)", want: "4 m"},
+		{name: "negative", expr: "-2 min in s", want: "-120 s"},
+		{name: "minute and min function", expr: "min(3 min, 100 s)", want: "1.6666666666666667 min"},
+		{name: "floor in unit", expr: "floor(5.7 km)", want: "5 km"},
+		{name: "prefixes", expr: "1 µs + 1 ns in ps", want: "1001000 ps"},
+		{name: "plain number", expr: "2 + 2", want: "4"},
+		{name: "variable with unit", expr: "x kg * 2", vars: Vars{"x": 3}, want: "6 kg"},
+		{name: "function of dimensionless", expr: "sin(0) + 1", want: "1"},
+		{name: "conversion in group", expr: "(1 inch in cm) * 2", want: "5.08 cm"},
+		{name: "mismatched sum", expr: "3 m + 2 s", wantErr: ErrDimensionMismatch},
+		{name: "mismatched conversion", expr: "3 m in kg", wantErr: ErrDimensionMismatch},
+		{name: "function of dimension", expr: "sin(2 m)", wantErr: ErrDimensionMismatch},
+		{name: "fractional power of unit", expr: "(2 s)^0.5", wantErr: ErrDimensionMismatch},
+		{name: "exponent with unit", expr: "2^(1 s)", wantErr: ErrDimensionMismatch},
+		{name: "conversion to number", expr: "3 m in 2", wantErr: ErrExpectedUnit},
+		{name: "missing target", expr: "3 m in", wantErr: ErrEndsWithOperator},
+		{name: "unit name as variable", expr: "m", vars: Vars{"m": 1}, wantErr: ErrInvalidVariableName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testQuantityCalc{testCalc: testCalc{expr: tt.expr}}
+			err := CalculateExpression(c, tt.vars, WithUnits(true))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := FormatQuantity(c.result, c.unit); got != tt.want {
+				t.Errorf("CalculateExpression(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitsAreOptIn(t *testing.T) {
+	err := CalculateExpression(&testCalc{expr: "5 km"}, nil)
+	if !errors.Is(err, ErrMissingOperator) {
+		t.Errorf("CalculateExpression(%q) error = %v, want %v", "5 km", err, ErrMissingOperator)
+	}
+
+	err = CalculateExpression(&testCalc{expr: "5 km"}, nil, WithUnits(true), WithPrecision(PrecisionExact))
+	if !errors.Is(err, ErrUnitsPrecision) {
+		t.Errorf("CalculateExpression(%q) in exact mode error = %v, want %v", "5 km", err, ErrUnitsPrecision)
+	}
+
+	// без QuantityCalculable в SetResult приходит значение в единице результата
+	c := &testCalc{expr: "1500 m in km"}
+	if err := CalculateExpression(c, nil, WithUnits(true)); err != nil || c.result != 1.5 {
+		t.Errorf("CalculateExpression(%q) with units = %v, %v, want 1.5 via SetResult", c.expr, c.result, err)
+	}
+}
+
+type testCurrencyCalc struct {
+	testQuantityCalc
+	currencies []string
+}
+
+func (c *testCurrencyCalc) SetCurrencies(codes []string) {
+	c.currencies = codes
+}
+
+func TestCalculateExpressionCurrencies(t *testing.T) {
+	rates := Rates{"RUB": 1, "USD": 90, "EUR": 100}
+
+	tests := []struct {
+		name           string
+		expr           string
+		units          bool
+		vars           Vars
+		want           string
+		wantCurrencies []string
+		wantErr        error
+	}{
+		{name: "sum in left currency", expr: "100 USD + 90 EUR", want: "200 USD", wantCurrencies: []string{"EUR", "USD"}},
+		{name: "conversion", expr: "100 USD + 50 EUR in RUB", want: "14000 RUB", wantCurrencies: []string{"EUR", "RUB", "USD"}},
+		{name: "price per unit", expr: "3 kg * 2 EUR/kg in USD", units: true, want: "6.666666666666667 USD", wantCurrencies: []string{"EUR", "USD"}},
+		{name: "ratio keeps both currencies", expr: "1 EUR / 1 USD", want: "1 EUR/USD", wantCurrencies: []string{"EUR", "USD"}},
+		{name: "without currencies", expr: "2 * 3", want: "6"},
+		{name: "unit names are variables without units", expr: "m * 2", vars: Vars{"m": 3}, want: "6"},
+		{name: "conversion keywords are variables without conversion", expr: "in * to", vars: Vars{"in": 2, "to": 3}, want: "6"},
+		{name: "unknown currency", expr: "100 GBP", wantErr: ErrMissingOperator},
+		{name: "currency and number", expr: "100 USD + 1", wantErr: ErrDimensionMismatch},
+		{name: "currency to length", expr: "100 USD in m", units: true, wantErr: ErrDimensionMismatch},
+		{name: "currency as variable", expr: "USD", vars: Vars{"USD": 1}, wantErr: ErrInvalidVariableName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &testCurrencyCalc{testQuantityCalc: testQuantityCalc{testCalc: testCalc{expr: tt.expr}}}
+			err := CalculateExpression(c, tt.vars, WithRates(rates), WithUnits(tt.units))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := FormatQuantity(c.result, c.unit); got != tt.want {
+				t.Errorf("CalculateExpression(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+			if !reflect.DeepEqual(c.currencies, tt.wantCurrencies) {
+				t.Errorf("CalculateExpression(%q) currencies = %v, want %v", tt.expr, c.currencies, tt.wantCurrencies)
+			}
+		})
+	}
+}
+
+func TestRatesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates Rates
+		want  error
+	}{
+		{name: "valid", rates: Rates{"RUB": 1, "USD": 92.5}},
+		{name: "empty", rates: Rates{}},
+		{name: "lowercase code", rates: Rates{"usd": 1}, want: ErrInvalidCurrency},
+		{name: "long code", rates: Rates{"USDT": 1}, want: ErrInvalidCurrency},
+		{name: "zero rate", rates: Rates{"USD": 0}, want: ErrInvalidRate},
+		{name: "negative rate", rates: Rates{"USD": -1}, want: ErrInvalidRate},
+		{name: "nan rate", rates: Rates{"USD": math.NaN()}, want: ErrInvalidRate},
+		{name: "infinite rate", rates: Rates{"USD": math.Inf(1)}, want: ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rates.Validate(); !errors.Is(err, tt.want) {
+				t.Errorf("Rates.Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Compile("100 USD", WithRates(Rates{"USD": 1}), WithPrecision(PrecisionExact)); !errors.Is(err, ErrUnitsPrecision) {
+		t.Errorf("Compile() with rates in exact mode error = %v, want %v", err, ErrUnitsPrecision)
+	}
+
+	// курсы без валют в выражении не отключают байткод
+	p, err := Compile("x * 2 + 1", WithRates(Rates{"USD": 1}), WithUnits(true))
+	if err != nil || p.code == nil {
+		t.Errorf("Compile() with rates but without currencies = %v, code %v, want bytecode", err, p.code != nil)
+	}
+}
+
+func TestMentionsCurrency(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "100 USD in RUB", want: true},
+		{expr: "2*EUR", want: true},
+		{expr: "1 + 2", want: false},
+		{expr: "x in m", want: false},
+		{expr: "USDT + usd", want: false},
+		{expr: "x_USD + USD1", want: false},
+		{expr: "√USD", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := MentionsCurrency(tt.expr); got != tt.want {
+				t.Errorf("MentionsCurrency(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		opts    []Option
+		want    float64
+		wantErr error
+	}{
+		{name: "literal", expr: "10%", want: 0.1},
+		{name: "add percent of left operand", expr: "200 + 10%", want: 220},
+		{name: "subtract percent of left operand", expr: "200 - 10%", want: 180},
+		{name: "chained", expr: "200 + 10% - 10%", want: 198},
+		{name: "percent before operator", expr: "10% + 5", want: 5.1},
+		{name: "percent times number", expr: "5 + 10% * 2", want: 5.2},
+		{name: "multiply by percent", expr: "200 * 10%", want: 20},
+		{name: "divide by percent", expr: "50 / 25%", want: 200},
+		{name: "percent of", expr: "50% of 80", want: 40},
+		{name: "of binds like multiplication", expr: "50% of 80 + 1", want: 41},
+		{name: "add percent of", expr: "200 + 10% of 50", want: 205},
+		{name: "percent of group", expr: "(10 + 15)% of 80", want: 20},
+		{name: "negative percent", expr: "-10% of 50", want: -5},
+		{name: "add negative percent", expr: "200 + -10%", want: 180},
+		{name: "percent change", expr: "100 -> 125", want: 25},
+		{name: "percent decrease", expr: "80 -> 60", want: -25},
+		{name: "change is weaker than all operators", expr: "50 * 2 -> 100 + 25", want: 25},
+		{name: "percent in arguments", expr: "max(5%, 1%)", want: 0.05},
+		{name: "variables", expr: "price + vat%", vars: Vars{"price": 100, "vat": 20}, want: 120},
+		{name: "modulo", expr: "10 % 4", want: 2},
+		{name: "modulo by negative in brackets", expr: "7 % (-3)", want: 1},
+		{name: "exact", expr: "15% of 0.1", opts: []Option{WithPrecision(PrecisionExact)}, want: 0.015},
+		{name: "complex", expr: "(2+4i) + 50% -> 6", opts: []Option{WithPrecision(PrecisionComplex)}, wantErr: ErrNotReal},
+		{name: "complex real", expr: "sqrt(-4)^2 + 10%", opts: []Option{WithPrecision(PrecisionComplex)}, want: -4.4},
+		{name: "units", expr: "5 km + 10% in m", opts: []Option{WithUnits(true)}, want: 5500},
+		{name: "change from zero", expr: "0 -> 5", wantErr: ErrDivisionByZero},
+		{name: "of without percent", expr: "5 of 80", wantErr: ErrExpectedPercent},
+		{name: "percent without operand", expr: "%", wantErr: ErrStartsWithOperator},
+		{name: "integer mode has no percent", expr: "10%", opts: []Option{WithPrecision(PrecisionInteger)}, wantErr: ErrEndsWithOperator},
+		{name: "integer mode has no change", expr: "1 -> 2", opts: []Option{WithPrecision(PrecisionInteger)}, wantErr: ErrConsecutiveOperators},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr, tt.opts...)
+			if err == nil {
+				var got float64
+				got, err = p.Eval(tt.vars)
+				if err == nil && math.Abs(got-tt.want) > 1e-12 {
+					t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Eval(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculateExpressionLogic(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		vars     Vars
+		opts     []Option
+		want     float64
+		wantType ValueType
+		wantErr  error
+	}{
+		{name: "comparison", expr: "1 + 2 > 2", want: 1, wantType: ValueBoolean},
+		{name: "equality", expr: "x == 2", vars: Vars{"x": 2}, want: 1, wantType: ValueBoolean},
+		{name: "and binds tighter than or", expr: "1 > 2 && 1 > 2 || 3 >= 3", want: 1, wantType: ValueBoolean},
+		{name: "not", expr: "!(x < 5)", vars: Vars{"x": 1}, want: 0, wantType: ValueBoolean},
+		{name: "boolean equality", expr: "(1 < 2) == (3 < 4)", want: 1, wantType: ValueBoolean},
+		{name: "numbers are truthy", expr: "x && y", vars: Vars{"x": 2, "y": 0}, want: 0, wantType: ValueBoolean},
+		{name: "ternary", expr: "x > 0 ? x : -x", vars: Vars{"x": -3}, want: 3, wantType: ValueNumber},
+		{name: "ternary is right associative", expr: "x ? 1 : y ? 2 : 3", vars: Vars{"x": 0, "y": 1}, want: 2, wantType: ValueNumber},
+		{name: "if", expr: "if(x >= 1, 2, 3) * 10", vars: Vars{"x": 1}, want: 20, wantType: ValueNumber},
+		{name: "boolean branches", expr: "x ? x > 1 : x < 1", vars: Vars{"x": 0}, want: 1, wantType: ValueBoolean},
+		{name: "and short-circuits", expr: "x != 0 && 1/x > 1", vars: Vars{"x": 0}, want: 0, wantType: ValueBoolean},
+		{name: "or short-circuits", expr: "x == 0 || 1/x > 1", vars: Vars{"x": 0}, want: 1, wantType: ValueBoolean},
+		{name: "only chosen branch is evaluated", expr: "x == 0 ? 0 : 1/x", vars: Vars{"x": 0}, want: 0, wantType: ValueNumber},
+		{name: "exact", expr: "0.1 + 0.2 == 0.3", opts: []Option{WithPrecision(PrecisionExact)}, want: 1, wantType: ValueBoolean},
+		{name: "float", expr: "0.1 + 0.2 == 0.3", want: 0, wantType: ValueBoolean},
+		{name: "integer", expr: "0xFF > 1 << 7 && 3 != 4", opts: []Option{WithPrecision(PrecisionInteger)}, want: 1, wantType: ValueBoolean},
+		{name: "complex equality", expr: "i*i == -1", opts: []Option{WithPrecision(PrecisionComplex)}, want: 1, wantType: ValueBoolean},
+		{name: "complex ordering", expr: "1 + i > 1", opts: []Option{WithPrecision(PrecisionComplex)}, wantType: ValueBoolean, wantErr: ErrNotReal},
+		{name: "units", expr: "1 mi > 1 km", opts: []Option{WithUnits(true)}, want: 1, wantType: ValueBoolean},
+		{name: "unit branches", expr: "2 > 1 ? 3 m : 4 cm", opts: []Option{WithUnits(true)}, want: 3, wantType: ValueNumber},
+		{name: "incompatible units", expr: "1 m > 1 s", opts: []Option{WithUnits(true)}, wantType: ValueBoolean, wantErr: ErrDimensionMismatch},
+		{name: "boolean in arithmetic", expr: "(1 < 2) + 1", wantErr: ErrTypeMismatch},
+		{name: "chained comparison", expr: "1 < 2 < 3", wantErr: ErrTypeMismatch},
+		{name: "boolean equals number", expr: "(1 < 2) == 1", wantErr: ErrTypeMismatch},
+		{name: "branches of different types", expr: "x ? 1 : 2 > 1", wantErr: ErrTypeMismatch},
+		{name: "boolean argument", expr: "sqrt(1 > 0)", wantErr: ErrTypeMismatch},
+		{name: "conditional without colon", expr: "1 ? 2", wantErr: ErrUnclosedConditional},
+		{name: "conditional without branch", expr: "1 ?: 2", wantErr: ErrStartsWithOperator},
+		{name: "if arity", expr: "if(1, 2)", wantErr: ErrArgumentCount},
+		{name: "if without brackets", expr: "if", wantErr: ErrMissingCallBrackets},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr, tt.opts...)
+			if err == nil {
+				if p.Type() != tt.wantType {
+					t.Errorf("Compile(%q).Type() = %v, want %v", tt.expr, p.Type(), tt.wantType)
+				}
+				var got float64
+				got, err = p.Eval(tt.vars)
+				if err == nil && math.Abs(got-tt.want) > 1e-12 {
+					t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Eval(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBooleanCalculable(t *testing.T) {
+	c := &testBoolCalc{testCalc: testCalc{expr: "2 > 1 && 3 < 1"}}
+	if err := CalculateExpression(c, nil); err != nil {
+		t.Fatalf("CalculateExpression(%q) error = %v", c.expr, err)
+	}
+	if c.boolean == nil || *c.boolean {
+		t.Errorf("CalculateExpression(%q) boolean = %v, want false", c.expr, c.boolean)
+	}
+
+	c = &testBoolCalc{testCalc: testCalc{expr: "2 > 1 ? 5 : 6"}}
+	if err := CalculateExpression(c, nil); err != nil {
+		t.Fatalf("CalculateExpression(%q) error = %v", c.expr, err)
+	}
+	if c.boolean != nil || c.result != 5 {
+		t.Errorf("CalculateExpression(%q) = %v, boolean %v, want 5", c.expr, c.result, c.boolean)
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	tests := []complex128{0, 1, -0.5, 2i, -2i, 11 - 2i, 1e-20 + 1e20i}
+	for _, z := range tests {
+		got := FormatComplex(z)
+		parsed, err := strconv.ParseComplex(got, 128)
+		if err != nil || parsed != z {
+			t.Errorf("ParseComplex(FormatComplex(%v)) = %v, %v, want %v", z, parsed, err, z)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		format NumberFormat
+		want   string
+	}{
+		{name: "auto", value: "1234.5", format: NumberFormat{Decimals: AutoScale}, want: "1234.5"},
+		{name: "decimals", value: "2/3", format: NumberFormat{Decimals: 2}, want: "0.67"},
+		{name: "decimals truncate", value: "2/3", format: NumberFormat{Decimals: 2, Rounding: RoundTruncate}, want: "0.66"},
+		{name: "decimals half-up", value: "0.125", format: NumberFormat{Decimals: 2, Rounding: RoundHalfUp}, want: "0.13"},
+		{name: "decimals half-even", value: "0.125", format: NumberFormat{Decimals: 2}, want: "0.12"},
+		{name: "digits", value: "2/3", format: NumberFormat{Digits: 3}, want: "0.667"},
+		{name: "digits keep zeros", value: "2", format: NumberFormat{Digits: 3}, want: "2.00"},
+		{name: "digits small", value: "0.00123456", format: NumberFormat{Digits: 2}, want: "0.0012"},
+		{name: "digits above point", value: "12345", format: NumberFormat{Digits: 2}, want: "12000"},
+		{name: "digits carry", value: "9.99", format: NumberFormat{Digits: 2}, want: "10"},
+		{name: "digits negative", value: "-98765", format: NumberFormat{Digits: 3}, want: "-98800"},
+		{name: "zero", value: "0", format: NumberFormat{Digits: 3}, want: "0.00"},
+		{name: "scientific", value: "123456", format: NumberFormat{Digits: 2, Notation: NotationScientific}, want: "1.2e5"},
+		{name: "scientific small", value: "0.000321", format: NumberFormat{Decimals: AutoScale, Notation: NotationScientific}, want: "3.21e-4"},
+		{name: "scientific carry", value: "9.996", format: NumberFormat{Decimals: 2, Notation: NotationScientific}, want: "1.00e1"},
+		{name: "scientific zero", value: "0", format: NumberFormat{Decimals: 1, Notation: NotationScientific}, want: "0.0e0"},
+		{name: "engineering", value: "123456", format: NumberFormat{Digits: 4, Notation: NotationEngineering}, want: "123.5e3"},
+		{name: "engineering small", value: "0.0123", format: NumberFormat{Decimals: AutoScale, Notation: NotationEngineering}, want: "12.3e-3"},
+		{name: "engineering carry", value: "999.96", format: NumberFormat{Digits: 3, Notation: NotationEngineering}, want: "1.00e3"},
+		{name: "grouping", value: "-1234567.891", format: NumberFormat{Decimals: 2, Grouping: true}, want: "-1,234,567.89"},
+		{name: "grouping short", value: "123", format: NumberFormat{Decimals: AutoScale, Grouping: true}, want: "123"},
+		{name: "grouping digits", value: "1234567", format: NumberFormat{Digits: 2, Grouping: true}, want: "1,200,000"},
+		{name: "locale ru", value: "-1234567.891", format: NumberFormat{Decimals: 2, Grouping: true, Locale: LocaleRussian}, want: "-1\u00a0234\u00a0567,89"},
+		{name: "locale de", value: "1234.5", format: NumberFormat{Decimals: AutoScale, Grouping: true, Locale: LocaleGerman}, want: "1.234,5"},
+		{name: "locale without grouping", value: "1234.5", format: NumberFormat{Decimals: AutoScale, Locale: LocaleFrench}, want: "1234,5"},
+		{name: "locale scientific", value: "123456", format: NumberFormat{Digits: 2, Notation: NotationScientific, Locale: LocaleRussian}, want: "1,2e5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := new(big.Rat).SetString(tt.value)
+			if got := FormatNumber(r, tt.format); got != tt.want {
+				t.Errorf("FormatNumber(%s, %+v) = %v, want %v", tt.value, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		locale    Locale
+		want      float64
+		canonical string
+		wantErr   error
+	}{
+		{name: "neutral", expr: "max(3.5, 1)", want: 3.5, canonical: "max(3.5, 1)"},
+		{name: "ru decimal comma", expr: "3,5 + 1,25", locale: LocaleRussian, want: 4.75, canonical: "3.5 + 1.25"},
+		{name: "ru dot is accepted", expr: "3.5 + ,5", locale: LocaleRussian, want: 4, canonical: "3.5 + .5"},
+		{name: "ru space groups", expr: "1 000 000,5 - 1", locale: LocaleRussian, want: 999999.5, canonical: "1_000_000.5 - 1"},
+		{name: "ru nbsp groups", expr: "2\u00a0500 * 2", locale: LocaleRussian, want: 5000, canonical: "2_500 * 2"},
+		{name: "ru arguments", expr: "max(1,5; 2,5)", locale: LocaleRussian, want: 2.5, canonical: "max(1.5, 2.5)"},
+		{name: "de groups", expr: "1.234,5 * 2", locale: LocaleGerman, want: 2469, canonical: "1_234.5 * 2"},
+		{name: "en", expr: "min(1.5,2)", locale: LocaleEnglish, want: 1.5, canonical: "min(1.5,2)"},
+		{name: "ru space without group", expr: "1 23", locale: LocaleRussian, wantErr: ErrMissingOperator},
+		{name: "ru comma is not a separator", expr: "max(pi, 2)", locale: LocaleRussian, wantErr: ErrMissingDigits},
+		{name: "ru double comma", expr: "1,,5", locale: LocaleRussian, wantErr: ErrDoubleDot},
+		{name: "de misplaced group", expr: "1.5", locale: LocaleGerman, wantErr: ErrMisplacedGroup},
+		{name: "de long first group", expr: "1234.567", locale: LocaleGerman, wantErr: ErrMisplacedGroup},
+		{name: "unknown", expr: "1", locale: "xx", wantErr: ErrUnknownLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr, WithLocale(tt.locale))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Compile(%q) error = %v, want %v", tt.expr, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.expr, err)
+			}
+
+			if got := p.Canonical(); got != tt.canonical {
+				t.Errorf("Canonical() = %q, want %q", got, tt.canonical)
+			}
+			got, err := p.Eval(nil)
+			if err != nil || got != tt.want {
+				t.Errorf("Eval() = %v, %v, want %v", got, err, tt.want)
+			}
+
+			// запись без локали даёт тот же результат
+			neutral, err := Compile(p.Canonical())
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", p.Canonical(), err)
+			}
+			if got, _ := neutral.Eval(nil); got != tt.want {
+				t.Errorf("canonical Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		opts       []Option
+		normalized string
+		want       float64
+	}{
+		{name: "ascii", expr: "2*3", normalized: "2*3", want: 6},
+		{name: "multiplication and division", expr: "6 × 2 ÷ 4 · 3", normalized: "6 * 2 / 4 * 3", want: 9},
+		{name: "minus sign", expr: "−2 − 3", normalized: "-2 - 3", want: -5},
+		{name: "square root", expr: "√16 + √(4+5)", normalized: "sqrt(16) + sqrt(4+5)", want: 7},
+		{name: "cube root", expr: "∛27", normalized: "cbrt(27)", want: 3},
+		{name: "root of variable", expr: "√x", normalized: "sqrt(x)", want: 3},
+		{name: "superscripts", expr: "2¹⁰ + 3²", normalized: "2^10 + 3^2", want: 1033},
+		{name: "negative superscript", expr: "10⁻²", normalized: "10^-2", want: 0.01},
+		{name: "root binds to operand", expr: "√4²", normalized: "sqrt(4)^2", want: 4},
+		{name: "nested roots", expr: "√√16 + ∛√64", normalized: "sqrt(sqrt(16)) + cbrt(sqrt(64))", want: 4},
+		{name: "nested root of group", expr: "√√(8 × 2)", normalized: "sqrt(sqrt(8 * 2))", want: 2},
+		{name: "root of negative", expr: "∛-8 + ∛−(8)", normalized: "cbrt(-8) + cbrt(-(8))", want: -4},
+		{name: "complex root of negative", expr: "√-4 × √-4", opts: []Option{WithPrecision(PrecisionComplex)}, normalized: "sqrt(-4) * sqrt(-4)", want: -4},
+		{name: "non-breaking spaces", expr: "1\u00a0+\u202f2", normalized: "1 + 2", want: 3},
+		{name: "ru root with decimal comma", expr: "√2,25 × 2", opts: []Option{WithLocale(LocaleRussian)}, normalized: "sqrt(2,25) * 2", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr, tt.opts...)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.expr, err)
+			}
+			if got := p.Normalized(); got != tt.normalized {
+				t.Errorf("Normalized() = %q, want %q", got, tt.normalized)
+			}
+			if got := p.Expression(); got != tt.expr {
+				t.Errorf("Expression() = %q, want %q", got, tt.expr)
+			}
+			if got, err := p.Eval(Vars{"x": 9}); err != nil || math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Eval() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		opts       []Option
+		wantErr    error
+		wantOffset int
+		wantToken  string
+	}{
+		// ошибка указывает на место в исходной записи, а не в нормализованной
+		{name: "after root", expr: "√9 × × 2", wantErr: ErrConsecutiveOperators, wantOffset: 5, wantToken: "*"},
+		{name: "end of expression", expr: "2² −", wantErr: ErrEndsWithOperator, wantOffset: 4},
+		{name: "root without operand", expr: "1 + √", wantErr: ErrArgumentCount, wantOffset: 4, wantToken: "sqrt"},
+		{name: "root of root without operand", expr: "√√", wantErr: ErrArgumentCount, wantOffset: 1, wantToken: "sqrt"},
+		{name: "root before operator", expr: "√ + 1", wantErr: ErrArgumentCount, wantOffset: 0, wantToken: "sqrt"},
+		{name: "no power in integer mode", expr: "2²", opts: []Option{WithPrecision(PrecisionInteger)}, wantErr: ErrInvalidCharacter, wantOffset: 1, wantToken: "²"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expr, tt.opts...)
+			var parseErr *ParseError
+			if !errors.Is(err, tt.wantErr) || !errors.As(err, &parseErr) {
+				t.Fatalf("Compile(%q) error = %v, want %v", tt.expr, err, tt.wantErr)
+			}
+			if parseErr.Expression != tt.expr || parseErr.Offset != tt.wantOffset || parseErr.Token != tt.wantToken {
+				t.Errorf("ParseError = {%q %d %q}, want {%q %d %q}",
+					parseErr.Expression, parseErr.Offset, parseErr.Token, tt.expr, tt.wantOffset, tt.wantToken)
+			}
+			if want := len(string([]rune(tt.expr)[:tt.wantOffset])); parseErr.ByteOffset != want {
+				t.Errorf("ByteOffset = %d, want %d", parseErr.ByteOffset, want)
+			}
+		})
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    Locale
+		wantErr error
+	}{
+		{tag: "", want: LocaleNeutral},
+		{tag: "ru", want: LocaleRussian},
+		{tag: "ru-RU", want: LocaleRussian},
+		{tag: "DE_at", want: LocaleGerman},
+		{tag: "ja", wantErr: ErrUnknownLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := ParseLocale(tt.tag)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("ParseLocale(%q) = %q, %v, want %q, %v", tt.tag, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatFraction(t *testing.T) {
+	tests := []struct {
+		value    string
+		fraction string
+		mixed    string
+	}{
+		{value: "1/2", fraction: "1/2", mixed: "1/2"},
+		{value: "2/4", fraction: "1/2", mixed: "1/2"},
+		{value: "7/2", fraction: "7/2", mixed: "3 1/2"},
+		{value: "-7/2", fraction: "-7/2", mixed: "-3 1/2"},
+		{value: "-1/3", fraction: "-1/3", mixed: "-1/3"},
+		{value: "6/3", fraction: "2", mixed: "2"},
+		{value: "0", fraction: "0", mixed: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			r, _ := new(big.Rat).SetString(tt.value)
+			if got := FormatFraction(r); got != tt.fraction {
+				t.Errorf("FormatFraction(%s) = %v, want %v", tt.value, got, tt.fraction)
+			}
+			if got := FormatMixed(r); got != tt.mixed {
+				t.Errorf("FormatMixed(%s) = %v, want %v", tt.value, got, tt.mixed)
+			}
+		})
+	}
+}
+
+func TestFormatInteger(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int64
+		intType IntType
+		base    int
+		want    string
+	}{
+		{name: "decimal", value: -1, intType: Int8, base: 10, want: "-1"},
+		{name: "hex", value: 255, intType: Uint8, base: 16, want: "0xff"},
+		{name: "binary", value: 10, intType: Int32, base: 2, want: "0b1010"},
+		{name: "octal", value: 15, intType: Int64, base: 8, want: "0o17"},
+		{name: "negative hex is twos complement", value: -1, intType: Int8, base: 16, want: "0xff"},
+		{name: "negative binary is twos complement", value: -2, intType: Int16, base: 2, want: "0b1111111111111110"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatInteger(big.NewInt(tt.value), tt.intType, tt.base); got != tt.want {
+				t.Errorf("FormatInteger(%d, %s, %d) = %v, want %v", tt.value, tt.intType, tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	p, err := Compile("price * qty + fee - price")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if got, want := p.Variables(), []string{"fee", "price", "qty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Program.Variables() = %v, want %v", got, want)
+	}
+	p.Variables()[0] = "changed"
+	if got := p.Variables()[0]; got != "fee" {
+		t.Errorf("Program.Variables() exposes internal state, got %q after change", got)
+	}
+
+	rows := []struct {
+		vars    Vars
+		want    float64
+		wantErr error
+	}{
+		{vars: Vars{"price": 2, "qty": 3, "fee": 1}, want: 5},
+		{vars: Vars{"price": 10, "qty": 0.5, "fee": 0}, want: -5},
+		{vars: Vars{"price": 1, "qty": 1}, wantErr: ErrUndefinedVariable},
+		{vars: Vars{"price": 1, "qty": 1, "fee": 1, "pi": 3}, wantErr: ErrInvalidVariableName},
+	}
+	for _, row := range rows {
+		got, err := p.Eval(row.vars)
+		if !errors.Is(err, row.wantErr) {
+			t.Errorf("Program.Eval(%v) error = %v, wantErr %v", row.vars, err, row.wantErr)
+			continue
+		}
+		if got != row.want {
+			t.Errorf("Program.Eval(%v) = %v, want %v", row.vars, got, row.want)
+		}
+	}
+
+	// price, qty, fee по алфавиту: fee, price, qty
+	if got, err := p.EvalValues([]float64{1, 2, 3}); err != nil || got != 5 {
+		t.Errorf("Program.EvalValues() = %v, %v, want 5", got, err)
+	}
+	if _, err := p.EvalValues([]float64{1, 2}); !errors.Is(err, ErrValueCount) {
+		t.Errorf("Program.EvalValues() error = %v, want %v", err, ErrValueCount)
+	}
+
+	if _, err := Compile("1 +* x"); !errors.Is(err, ErrConsecutiveOperators) {
+		t.Errorf("Compile() error = %v, want %v", err, ErrConsecutiveOperators)
+	}
+	if _, err := Compile("1", WithPrecision("double")); !errors.Is(err, ErrUnknownPrecision) {
+		t.Errorf("Compile() error = %v, want %v", err, ErrUnknownPrecision)
+	}
+}
+
+func TestProgramModes(t *testing.T) {
+	exact, err := Compile("x + 0.2", WithPrecision(PrecisionExact))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	c := &testExactCalc{testCalc: testCalc{expr: exact.Expression()}}
+	if err := exact.Run(c, Vars{"x": 0.1}); err != nil {
+		t.Fatalf("Program.Run() error = %v", err)
+	}
+	if got := c.exact.RatString(); got != "3/10" {
+		t.Errorf("Program.Run() exact = %s, want 3/10", got)
+	}
+	if got, err := exact.Eval(Vars{"x": 0.1}); err != nil || got != 0.3 {
+		t.Errorf("Program.Eval() = %v, %v, want 0.3", got, err)
+	}
+
+	integer, err := Compile("x << 4", WithPrecision(PrecisionInteger), WithIntegerType(Uint8))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	ic := &testIntCalc{}
+	if err := integer.Run(ic, Vars{"x": 15}); err != nil || ic.integer.Int64() != 240 {
+		t.Errorf("Program.Run() integer = %v, %v, want 240", ic.integer, err)
+	}
+	if got, err := integer.Eval(Vars{"x": 16}); err != nil || got != 0 {
+		t.Errorf("Program.Eval() = %v, %v, want 0", got, err)
+	}
+	if got, err := integer.EvalValues([]float64{1}); err != nil || got != 16 {
+		t.Errorf("Program.EvalValues() = %v, %v, want 16", got, err)
+	}
+}
+
+func TestProgramConcurrentEval(t *testing.T) {
+	p, err := Compile("sqrt(x) * 2 + max(x, 1)")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := range 64 {
+		wg.Add(1)
+		go func(x float64) {
+			defer wg.Done()
+			got, err := p.Eval(Vars{"x": x})
+			if want := math.Sqrt(x)*2 + math.Max(x, 1); err != nil || got != want {
+				errs <- fmt.Errorf("Program.Eval(x=%v) = %v, %v, want %v", x, got, err, want)
+			}
+		}(float64(i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestBytecodeMatchesTreeWalker(t *testing.T) {
+	vars := Vars{"x": 0.1, "y": -3.7, "big": 1e300, "zero": 0}
+	exprs := []string{
+		"0.1+0.2", "x+0.2", "1/3*3", "x/3*3", "2^0.5^2", "-x^2", "y%x", "y//x", "--y",
+		"sin(x)^2+cos(x)^2", "sqrt(2)*sqrt(2)", "log(x, 7) + ln(x) + exp(y)", "atan2(y, x)",
+		"round(y*1000/7, 3)", "min(x, y, 1/7) + max(x, y, pi)", "pi*e*tau", "cbrt(y)+abs(y)+floor(y)+ceil(y)+trunc(y)",
+		"2*x + 3*(4 - x)/5", "1/zero", "x + 1/0", "big*big", "exp(big)", "sqrt(y)", "0^-1", "(-8)^(1/3)", "q + 1", "x + q",
+		"y + x%", "x% of y", "x -> y", "200 + 10% - x%", "zero -> x", "50% of 80",
+		"x < y || y <= x", "!(x == y) && zero", "zero && 1/zero", "x || 1/zero", "x > 0 ? y : 1/zero",
+		"zero ? 1/zero : if(y >= x, x, y)", "x != y ? x < y : x > y", "1 > 2 ? q : x",
+	}
+	for _, expr := range exprs {
+		t.Run(expr, func(t *testing.T) {
+			p, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", expr, err)
+			}
+
+			want, wantErr := evaluate(context.Background(), p.root, floatArithmetic{registry: builtins}, vars)
+			got, err := p.Eval(vars)
+			if fmt.Sprint(err) != fmt.Sprint(wantErr) {
+				t.Fatalf("bytecode error = %v, tree walker error = %v", err, wantErr)
+			}
+			if math.Float64bits(got) != math.Float64bits(want) {
+				t.Errorf("bytecode = %v, tree walker = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBytecodeFoldsConstants(t *testing.T) {
+	tests := []struct {
+		expr   string
+		consts int
+		code   int
+	}{
+		{expr: "2*pi*r", consts: 1, code: 3},
+		{expr: "sqrt(16) + max(1, 2, 3) - -1", consts: 1, code: 1},
+		{expr: "x + 1/0", consts: 2, code: 5}, // ошибка не сворачивается, она произойдёт при вычислении
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.expr, err)
+			}
+			if len(p.code.consts) != tt.consts || len(p.code.code) != tt.code {
+				t.Errorf("bytecode has %d consts and %d instructions, want %d and %d",
+					len(p.code.consts), len(p.code.code), tt.consts, tt.code)
+			}
+		})
+	}
+}
+
+func TestProgramEvalDoesNotAllocate(t *testing.T) {
+	p, err := Compile("price * qty * (1 - discount/100) + max(fee, 1.5) + sqrt(qty)")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	vars := Vars{"price": 9.99, "qty": 4, "discount": 15, "fee": 2}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := p.Eval(vars); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Program.Eval() allocates %v times per run, want 0", allocs)
+	}
+
+	values := []float64{15, 2, 9.99, 4}
+	allocs = testing.AllocsPerRun(100, func() {
+		if _, err := p.EvalValues(values); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Program.EvalValues() allocates %v times per run, want 0", allocs)
+	}
+}
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	r := NewRegistry()
+	for _, op := range []Operator{
+		{Symbol: "**", Arity: 2, Precedence: 4, RightAssoc: true, Apply: func(args []float64) (float64, error) { return pow(args[0], args[1]) }},
+		{Symbol: "<>", Arity: 2, Precedence: 1, Apply: func(args []float64) (float64, error) { return math.Abs(args[0] - args[1]), nil }},
+		{Symbol: "√", Arity: 1, Precedence: 3, Apply: func(args []float64) (float64, error) { return math.Sqrt(args[0]), nil }},
+	} {
+		if err := r.RegisterOperator(op); err != nil {
+			t.Fatalf("RegisterOperator(%q) error = %v", op.Symbol, err)
+		}
+	}
+
+	err := r.RegisterFunction(Function{Name: "avg", MinArgs: 1, MaxArgs: Variadic, Apply: func(args []float64) (float64, error) {
+		sum := 0.0
+		for _, arg := range args {
+			sum += arg
+		}
+		return sum / float64(len(args)), nil
+	}})
+	if err != nil {
+		t.Fatalf("RegisterFunction() error = %v", err)
+	}
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	r := testRegistry(t)
+
+	tests := []struct {
+		name      string
+		expr      string
+		vars      Vars
+		precision Precision
+		want      float64
+		wantErr   error
+	}{
+		{name: "right assoc", expr: "2 ** 3 ** 2", want: 512},
+		{name: "precedence", expr: "1 + 2 * 3 <> 10", want: 3},
+		{name: "left assoc", expr: "1 <> 5 <> 10", want: 6},
+		{name: "unary", expr: "√16 * 2", want: 8},
+		{name: "unary with power", expr: "√2^4", want: 4},
+		{name: "function", expr: "avg(1, 2, x)", vars: Vars{"x": 6}, want: 3},
+		{name: "builtins untouched", expr: "2 ^ 3 + max(1, 2)", want: 10},
+		{name: "function arity", expr: "avg()", wantErr: ErrArgumentCount},
+		{name: "function as variable", expr: "x + 1", vars: Vars{"avg": 1}, wantErr: ErrInvalidVariableName},
+		{name: "non-finite result", expr: "10 ** 400", wantErr: ErrOverflow},
+		{name: "exact operator", expr: "2 ** 2", precision: PrecisionExact, wantErr: ErrInexact},
+		{name: "exact function", expr: "avg(1, 2)", precision: PrecisionExact, wantErr: ErrInexact},
+		{name: "integer function", expr: "avg(1, 2)", precision: PrecisionInteger, wantErr: ErrIntegerFunction},
+		{name: "integer grammar", expr: "2 ** 2", precision: PrecisionInteger, wantErr: ErrConsecutiveOperators},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithRegistry(r)}
+			if tt.precision != "" {
+				opts = append(opts, WithPrecision(tt.precision))
+			}
+
+			c := &testCalc{expr: tt.expr}
+			err := CalculateExpression(c, tt.vars, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if err == nil && c.result != tt.want {
+				t.Errorf("CalculateExpression(%q) = %v, want %v", tt.expr, c.result, tt.want)
+			}
+		})
+	}
+
+	// встроенный реестр не меняется
+	if _, err := Compile("2 ** 2"); !errors.Is(err, ErrConsecutiveOperators) {
+		t.Errorf("Compile() without registry error = %v, want %v", err, ErrConsecutiveOperators)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	apply := func(args []float64) (float64, error) { return args[0], nil }
+
+	operators := []struct {
+		name    string
+		op      Operator
+		wantErr error
+	}{
+		{name: "empty symbol", op: Operator{Arity: 2, Precedence: 1, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "letter", op: Operator{Symbol: "x", Arity: 2, Precedence: 1, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "bracket", op: Operator{Symbol: "(", Arity: 1, Precedence: 1, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "dot", op: Operator{Symbol: ".", Arity: 2, Precedence: 1, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "arity", op: Operator{Symbol: "?", Arity: 3, Precedence: 1, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "precedence", op: Operator{Symbol: "?", Arity: 2, Apply: apply}, wantErr: ErrInvalidOperator},
+		{name: "no apply", op: Operator{Symbol: "?", Arity: 2, Precedence: 1}, wantErr: ErrInvalidOperator},
+		{name: "builtin binary", op: Operator{Symbol: "^", Arity: 2, Precedence: 1, Apply: apply}, wantErr: ErrOperatorExists},
+		{name: "builtin unary", op: Operator{Symbol: "-", Arity: 1, Precedence: 1, Apply: apply}, wantErr: ErrOperatorExists},
+		{name: "unary over binary", op: Operator{Symbol: "*", Arity: 1, Precedence: 3, Apply: apply}},
+	}
+	for _, tt := range operators {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewRegistry().RegisterOperator(tt.op); !errors.Is(err, tt.wantErr) {
+				t.Errorf("RegisterOperator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	functions := []struct {
+		name    string
+		fn      Function
+		wantErr error
+	}{
+		{name: "invalid name", fn: Function{Name: "2x", MinArgs: 1, MaxArgs: 1, Apply: apply}, wantErr: ErrInvalidFunction},
+		{name: "no apply", fn: Function{Name: "f", MinArgs: 1, MaxArgs: 1}, wantErr: ErrInvalidFunction},
+		{name: "max below min", fn: Function{Name: "f", MinArgs: 2, MaxArgs: 1, Apply: apply}, wantErr: ErrInvalidFunction},
+		{name: "builtin", fn: Function{Name: "sin", MinArgs: 1, MaxArgs: 1, Apply: apply}, wantErr: ErrFunctionExists},
+		{name: "constant", fn: Function{Name: "pi", MinArgs: 1, MaxArgs: 1, Apply: apply}, wantErr: ErrFunctionExists},
+		{name: "valid", fn: Function{Name: "f", MinArgs: 1, MaxArgs: Variadic, Apply: apply}},
+	}
+	for _, tt := range functions {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewRegistry().RegisterFunction(tt.fn); !errors.Is(err, tt.wantErr) {
+				t.Errorf("RegisterFunction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryBytecode(t *testing.T) {
+	r := testRegistry(t)
+	vars := Vars{"x": 0.1, "y": -3.7}
+
+	for _, expr := range []string{"x ** y <> √x", "avg(x, y, √2) ** 2", "-√x ** 0.5", "√y", "2 ** 3 <> 1"} {
+		t.Run(expr, func(t *testing.T) {
+			p, err := Compile(expr, WithRegistry(r))
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", expr, err)
+			}
+
+			want, wantErr := evaluate(context.Background(), p.root, floatArithmetic{registry: r}, vars)
+			got, err := p.Eval(vars)
+			if fmt.Sprint(err) != fmt.Sprint(wantErr) {
+				t.Fatalf("bytecode error = %v, tree walker error = %v", err, wantErr)
+			}
+			if math.Float64bits(got) != math.Float64bits(want) {
+				t.Errorf("bytecode = %v, tree walker = %v", got, want)
+			}
+
+			allocs := testing.AllocsPerRun(100, func() { _, _ = p.Eval(vars) })
+			if allocs != 0 && err == nil {
+				t.Errorf("Program.Eval() allocates %v times per run, want 0", allocs)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxLength: 40, MaxTokens: 15, MaxDepth: 4, MaxOperations: 5}
+
+	tests := []struct {
+		name   string
+		expr   string
+		limits *Limits // nil - DefaultLimits
+		want   Limit   // "" - без ошибки
+	}{
+		{name: "within limits", expr: "max(1, 2) + (3 * 4) - 5", limits: &limits},
+		{name: "length", expr: strings.Repeat("1", 41), limits: &limits, want: LimitLength},
+		{name: "tokens", expr: "((1))+((1))+((1))", limits: &limits, want: LimitTokens},
+		{name: "depth", expr: "((((1))))", limits: &limits, want: LimitDepth},
+		{name: "unary depth", expr: "- - - - 1", limits: &limits, want: LimitDepth},
+		{name: "operations", expr: "1+2+3+4+5+6+7", limits: &limits, want: LimitOperations},
+		{name: "calls are operations", expr: "-abs(1) * -abs(1) * 1", limits: &limits, want: LimitOperations},
+		{name: "no limits", expr: strings.Repeat("1+", 6_000) + "1", limits: &Limits{}},
+		{name: "depth is always limited", expr: strings.Repeat("(", 1_000) + "1" + strings.Repeat(")", 1_000), limits: &Limits{}, want: LimitDepth},
+		{name: "negative depth", expr: strings.Repeat("-", 1_000) + "1", limits: &Limits{MaxDepth: -1}, want: LimitDepth},
+		{name: "default depth", expr: strings.Repeat("(", 1_000) + "1" + strings.Repeat(")", 1_000), want: LimitDepth},
+		{name: "default length", expr: strings.Repeat("1+", 6_000) + "1", want: LimitLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.limits != nil {
+				opts = append(opts, WithLimits(*tt.limits))
+			}
+
+			_, err := Compile(tt.expr, opts...)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Compile() error = %v", err)
+				}
+				return
+			}
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrTooComplex) {
+				t.Fatalf("Compile() error = %v, want *LimitError", err)
+			}
+			if limitErr.Limit != tt.want {
+				t.Errorf("LimitError.Limit = %v, want %v", limitErr.Limit, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, precision := range []Precision{PrecisionFloat, PrecisionExact, PrecisionInteger} {
+		t.Run(string(precision), func(t *testing.T) {
+			p, err := Compile("abs(x) + 1", WithPrecision(precision))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+
+			if _, err := p.EvalContext(canceled, Vars{"x": 1}); !errors.Is(err, context.Canceled) {
+				t.Errorf("Program.EvalContext() error = %v, want %v", err, context.Canceled)
+			}
+			if err := p.RunContext(canceled, &testCalc{}, Vars{"x": 1}); !errors.Is(err, context.Canceled) {
+				t.Errorf("Program.RunContext() error = %v, want %v", err, context.Canceled)
+			}
+			if got, err := p.EvalContext(context.Background(), Vars{"x": -1}); err != nil || got != 2 {
+				t.Errorf("Program.EvalContext() = %v, %v, want 2", got, err)
+			}
+		})
+	}
+
+	// функция реестра, которая отменяет контекст: следующий вызов не выполняется
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	r := NewRegistry()
+	err := r.RegisterFunction(Function{Name: "slow", MinArgs: 1, MaxArgs: 1, Apply: func(args []float64) (float64, error) {
+		calls++
+		cancel()
+		return args[0], nil
+	}})
+	if err != nil {
+		t.Fatalf("RegisterFunction() error = %v", err)
+	}
+
+	c := &testCalc{expr: "slow(x) + slow(x)"}
+	if err := CalculateExpressionContext(ctx, c, Vars{"x": 1}, WithRegistry(r)); !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("CalculateExpressionContext() error = %v after %d calls, want %v after 1", err, calls, context.Canceled)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		vars    Vars
+		opts    []Option
+		want    []Step
+		wantErr error
+	}{
+		{
+			name: "float",
+			expr: "(1 + 2) * sqrt(16) - -x",
+			vars: Vars{"x": 1},
+			want: []Step{
+				{Expression: "1 + 2", Operator: "+", Operands: []string{"1", "2"}, Result: "3"},
+				{Expression: "sqrt(16)", Operator: "sqrt", Operands: []string{"16"}, Result: "4"},
+				{Expression: "(1 + 2) * sqrt(16)", Operator: "*", Operands: []string{"3", "4"}, Result: "12"},
+				{Expression: "-x", Operator: "-", Operands: []string{"1"}, Result: "-1"},
+				{Expression: "(1 + 2) * sqrt(16) - -x", Operator: "-", Operands: []string{"12", "-1"}, Result: "13"},
+			},
+		},
+		{
+			name: "constants are not folded",
+			expr: "2*pi",
+			want: []Step{{Expression: "2*pi", Operator: "*", Operands: []string{"2", "3.141592653589793"}, Result: "6.283185307179586"}},
+		},
+		{
+			name: "exact",
+			expr: "0.1 + 0.2",
+			opts: []Option{WithPrecision(PrecisionExact)},
+			want: []Step{{Expression: "0.1 + 0.2", Operator: "+", Operands: []string{"0.1", "0.2"}, Result: "0.3"}},
+		},
+		{
+			name: "integer",
+			expr: "0x7F + 1",
+			opts: []Option{WithPrecision(PrecisionInteger), WithIntegerType(Int8)},
+			want: []Step{{Expression: "0x7F + 1", Operator: "+", Operands: []string{"127", "1"}, Result: "-128"}},
+		},
+		{
+			name: "multibyte runes",
+			expr: "max(x, 2) × 3",
+			opts: []Option{WithRegistry(func() *Registry {
+				r := NewRegistry()
+				_ = r.RegisterOperator(Operator{Symbol: "×", Arity: 2, Precedence: 2, Apply: func(args []float64) (float64, error) {
+					return args[0] * args[1], nil
+				}})
+				return r
+			}())},
+			vars: Vars{"x": 1},
+			want: []Step{
+				{Expression: "max(x, 2)", Operator: "max", Operands: []string{"1", "2"}, Result: "2"},
+				{Expression: "max(x, 2) × 3", Operator: "×", Operands: []string{"2", "3"}, Result: "6"},
+			},
+		},
+		{
+			name: "percent",
+			expr: "200 + 10% -> 50% of 500",
+			want: []Step{
+				{Expression: "200 + 10%", Operator: "+%", Operands: []string{"200", "10"}, Result: "220", Interpretation: "200 + 200 * 10 / 100"},
+				{Expression: "50% of 500", Operator: "of", Operands: []string{"50", "500"}, Result: "250", Interpretation: "50 * 500 / 100"},
+				{Expression: "200 + 10% -> 50% of 500", Operator: "->", Operands: []string{"220", "250"}, Result: "13.636363636363635", Interpretation: "(250 - 220) / 220 * 100"},
+			},
+		},
+		{
+			name: "percent literal",
+			expr: "2 * 5%",
+			want: []Step{
+				{Expression: "5%", Operator: "%", Operands: []string{"5"}, Result: "0.05", Interpretation: "5 / 100"},
+				{Expression: "2 * 5%", Operator: "*", Operands: []string{"2", "0.05"}, Result: "0.1"},
+			},
+		},
+		{
+			name: "logic",
+			expr: "x > 0 && y == 0 ? 1 : 2",
+			vars: Vars{"x": 1, "y": 1},
+			want: []Step{
+				{Expression: "x > 0", Operator: ">", Operands: []string{"1", "0"}, Result: "true"},
+				{Expression: "y == 0", Operator: "==", Operands: []string{"1", "0"}, Result: "false"},
+				{Expression: "x > 0 && y == 0", Operator: "&&", Operands: []string{"true", "false"}, Result: "false"},
+				{Expression: "x > 0 && y == 0 ? 1 : 2", Operator: "?", Operands: []string{"false", "2"}, Result: "2"},
+			},
+		},
+		{name: "no steps", expr: "42", want: []Step{}},
+		{name: "math error", expr: "1 + 1/0", wantErr: ErrDivisionByZero},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExplainExpression(context.Background(), &testCalc{expr: tt.expr}, tt.vars, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExplainExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExplainExpression(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainMatchesRun(t *testing.T) {
+	vars := Vars{"x": 0.1, "y": -3.7}
+	for _, expr := range []string{"sin(x)^2+cos(x)^2", "2*x + 3*(4 - x)/5", "round(y*1000/7, 3)", "pi*e*tau"} {
+		t.Run(expr, func(t *testing.T) {
+			p, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", expr, err)
+			}
+
+			run, explained := &testCalc{}, &testCalc{}
+			if err := p.Run(run, vars); err != nil {
+				t.Fatalf("Program.Run() error = %v", err)
+			}
+			steps, err := p.Explain(context.Background(), explained, vars)
+			if err != nil {
+				t.Fatalf("Program.Explain() error = %v", err)
+			}
+			if math.Float64bits(run.result) != math.Float64bits(explained.result) {
+				t.Errorf("Program.Explain() result = %v, Program.Run() = %v", explained.result, run.result)
+			}
+			if last := steps[len(steps)-1]; last.Result != formatFloat(run.result) || last.Expression != expr {
+				t.Errorf("last step = %+v, want %q = %v", last, expr, run.result)
+			}
+		})
+	}
+}
+
+const benchExpr = "price * qty * (1 - discount/100) + max(fee, 1.5) + sqrt(qty) * 2^3"
+
+var benchVars = Vars{"price": 9.99, "qty": 4, "discount": 15, "fee": 2}
+
+// BenchmarkCalculateExpression - разбор и обход дерева на каждое вычисление
+func BenchmarkCalculateExpression(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := CalculateExpression(&testCalc{expr: benchExpr}, benchVars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTreeWalk - разбор один раз, обход дерева на каждое вычисление
+func BenchmarkTreeWalk(b *testing.B) {
+	cfg, err := newConfig(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := parse(benchExpr, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := evaluate(context.Background(), root, floatArithmetic{registry: builtins}, benchVars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchPercentExpr - проценты, сравнения и && тоже вычисляются без выделения памяти
+const benchPercentExpr = "price + fee% < discount && qty % 3 == 1 ? (price - discount%) * qty : fee"
+
+// BenchmarkProgramEval - байткод со свёрткой констант
+func BenchmarkProgramEval(b *testing.B) {
+	for _, expr := range []string{benchExpr, benchPercentExpr} {
+		b.Run(expr, func(b *testing.B) {
+			p, err := Compile(expr)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := p.Eval(benchVars); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			if allocs := testing.AllocsPerRun(100, func() { _, _ = p.Eval(benchVars) }); allocs != 0 {
+				b.Errorf("Program.Eval() allocates %v times per run, want 0", allocs)
+			}
+		})
+	}
+}
+
+// BenchmarkProgramEvalValues - байткод, переменные по номеру вместо поиска в map
+func BenchmarkProgramEvalValues(b *testing.B) {
+	p, err := Compile(benchExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	values := make([]float64, 0, len(benchVars))
+	for _, name := range p.Variables() {
+		values = append(values, benchVars[name])
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := p.EvalValues(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgramEvalParallel(b *testing.B) {
+	p, err := Compile(benchExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := p.Eval(benchVars); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
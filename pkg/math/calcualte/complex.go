@@ -0,0 +1,234 @@
+package calculable
+
+import (
+	"cmp"
+	"math"
+	"math/cmplx"
+	"strconv"
+)
+
+// ComplexCalculable - получатель результата в режиме PrecisionComplex.
+// Если Calculable его не реализует, в SetResult придёт вещественный результат, а мнимый - ErrNotReal.
+type ComplexCalculable interface {
+	Calculable
+	SetComplexResult(complex128)
+}
+
+// imaginaryUnit - константа режима PrecisionComplex, перекрывает переменную с тем же именем
+const imaginaryUnit = "i"
+
+// complexGrammar - операторы float и мнимые литералы 4i, 2.5i; операторов из Registry в этом режиме нет
+var complexGrammar = grammar{binary: floatGrammar.binary, unary: floatGrammar.unary, imaginary: true, percent: true}
+
+// maxIntegerPower - до этого показателя целая степень считается умножением, без погрешности cmplx.Pow: i^2 = -1
+const maxIntegerPower = 1024
+
+type complexFunction struct {
+	function // арность, apply не используется
+	apply    func(args []complex128) (complex128, error)
+}
+
+func complexUnary(f func(complex128) complex128) complexFunction {
+	return complexFunction{
+		function: function{minArgs: 1, maxArgs: 1},
+		apply:    func(args []complex128) (complex128, error) { return f(args[0]), nil },
+	}
+}
+
+// complexFunctions - функции с комплексным аргументом; остальные (floor, min, пользовательские)
+// вычисляются во float64 и принимают только вещественные аргументы
+var complexFunctions = map[string]complexFunction{
+	"sqrt": complexUnary(cmplx.Sqrt),
+	"cbrt": complexUnary(func(z complex128) complex128 {
+		// у вещественных - вещественный корень: cbrt(-8) = -2, а не главное значение 1+1.73i
+		if imag(z) == 0 {
+			return complex(math.Cbrt(real(z)), 0)
+		}
+		return cmplx.Pow(z, 1.0/3)
+	}),
+
+	"sin":  complexUnary(cmplx.Sin),
+	"cos":  complexUnary(cmplx.Cos),
+	"tan":  complexUnary(cmplx.Tan),
+	"asin": complexUnary(cmplx.Asin),
+	"acos": complexUnary(cmplx.Acos),
+	"atan": complexUnary(cmplx.Atan),
+
+	"ln":    {function: function{minArgs: 1, maxArgs: 1}, apply: func(args []complex128) (complex128, error) { return complexLog(args[0], math.E) }},
+	"log2":  {function: function{minArgs: 1, maxArgs: 1}, apply: func(args []complex128) (complex128, error) { return complexLog(args[0], 2) }},
+	"log10": {function: function{minArgs: 1, maxArgs: 1}, apply: func(args []complex128) (complex128, error) { return complexLog(args[0], 10) }},
+	"log":   {function: function{minArgs: 1, maxArgs: 2}, apply: complexLogarithm},
+	"exp":   complexUnary(cmplx.Exp),
+
+	"abs":  complexUnary(func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) }),
+	"re":   complexUnary(func(z complex128) complex128 { return complex(real(z), 0) }),
+	"im":   complexUnary(func(z complex128) complex128 { return complex(imag(z), 0) }),
+	"conj": complexUnary(cmplx.Conj),
+	"arg":  complexUnary(func(z complex128) complex128 { return complex(cmplx.Phase(z), 0) }),
+}
+
+func complexLog(z, base complex128) (complex128, error) {
+	if z == 0 || base == 0 || base == 1 {
+		return 0, ErrFunctionDomain
+	}
+	return cmplx.Log(z) / cmplx.Log(base), nil
+}
+
+// complexLogarithm - log(z) по основанию 10, log(z, base) по произвольному основанию
+func complexLogarithm(args []complex128) (complex128, error) {
+	if len(args) == 1 {
+		return complexLog(args[0], 10)
+	}
+	return complexLog(args[0], args[1])
+}
+
+type complexArithmetic struct {
+	registry *Registry // функции, которых нет в complexFunctions
+}
+
+func (complexArithmetic) number(n numberNode) (complex128, error) {
+	if n.imaginary {
+		return complex(0, n.value), nil
+	}
+	return complex(n.value, 0), nil
+}
+
+func (complexArithmetic) constant(n constantNode) (complex128, error) {
+	if n.name == imaginaryUnit {
+		return 1i, nil
+	}
+	return complex(n.value, 0), nil
+}
+
+func (complexArithmetic) variable(value float64) (complex128, error) {
+	return complex(value, 0), nil
+}
+
+func (complexArithmetic) unary(op string, z complex128) (complex128, error) {
+	switch op {
+	case "+":
+		return z, nil
+	case "-":
+		// 0 - z, а не -z: у -4 мнимая часть +0, и sqrt(-4) = 2i, а не -2i
+		return 0 - z, nil
+	default:
+		return 0, ErrUnknownOperator
+	}
+}
+
+func (complexArithmetic) binary(op string, a, b complex128) (complex128, error) {
+	switch op {
+	case "+":
+		return finiteComplex(a+b, nil)
+	case "-":
+		return finiteComplex(a-b, nil)
+	case "*":
+		return finiteComplex(a*b, nil)
+	case "/":
+		if b == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return finiteComplex(a/b, nil)
+	case "^":
+		return finiteComplex(complexPow(a, b))
+	case "%", "//":
+		// остаток и деление с округлением вниз определены только на прямой
+		if imag(a) != 0 || imag(b) != 0 {
+			return 0, ErrNotReal
+		}
+		operation := floatOperations[op]
+		res, err := finite(operation(real(a), real(b)))
+		return complex(res, 0), err
+	default:
+		return 0, ErrUnknownOperator
+	}
+}
+
+func (c complexArithmetic) call(name string, args []complex128) (complex128, error) {
+	if f, ok := complexFunctions[name]; ok {
+		return finiteComplex(f.apply(args))
+	}
+
+	reals := make([]float64, len(args))
+	for i, arg := range args {
+		if imag(arg) != 0 {
+			return 0, ErrNotReal
+		}
+		reals[i] = real(arg)
+	}
+	res, err := finite(c.registry.functions[name].apply(reals))
+	return complex(res, 0), err
+}
+
+// compare - равенство для любых чисел, порядок - только на прямой
+func (complexArithmetic) compare(op string, a, b complex128) (bool, error) {
+	switch {
+	case op == "==":
+		return a == b, nil
+	case op == "!=":
+		return a != b, nil
+	case imag(a) != 0 || imag(b) != 0:
+		return false, ErrNotReal
+	default:
+		return compareOrdered(op, cmp.Compare(real(a), real(b)))
+	}
+}
+
+// complexPow - вещественная степень вещественного числа считается как во float, чтобы 2^0.5 не получил
+// мнимую часть 1e-17, целая степень - умножением, остальное - главное значение cmplx.Pow
+func complexPow(a, b complex128) (complex128, error) {
+	if a == 0 && real(b) < 0 {
+		return 0, ErrDivisionByZero
+	}
+	if imag(a) == 0 && imag(b) == 0 && (real(a) >= 0 || real(b) == math.Trunc(real(b))) {
+		return complex(math.Pow(real(a), real(b)), 0), nil
+	}
+
+	n := real(b)
+	if imag(b) != 0 || n != math.Trunc(n) || math.Abs(n) > maxIntegerPower {
+		return cmplx.Pow(a, b), nil
+	}
+
+	res, base := complex(1, 0), a
+	for k := int(math.Abs(n)); k > 0; k >>= 1 {
+		if k&1 == 1 {
+			res *= base
+		}
+		base *= base
+	}
+	if n < 0 {
+		return 1 / res, nil
+	}
+	return res, nil
+}
+
+// finiteComplex - обе части результата должны быть конечными
+func finiteComplex(res complex128, err error) (complex128, error) {
+	switch {
+	case err != nil:
+		return 0, err
+	case cmplx.IsNaN(res):
+		return 0, ErrNotANumber
+	case cmplx.IsInf(res):
+		return 0, ErrOverflow
+	}
+	return res, nil
+}
+
+// FormatComplex - запись без потери точности, которую разбирает strconv.ParseComplex:
+// "11-2i", "2i", "-0.5"; вещественный результат - как у режима float
+func FormatComplex(z complex128) string {
+	re, im := real(z), imag(z)
+	if im == 0 {
+		return strconv.FormatFloat(re, 'f', -1, 64)
+	}
+
+	imText := strconv.FormatFloat(im, 'f', -1, 64) + "i"
+	if re == 0 {
+		return imText
+	}
+	if im > 0 {
+		imText = "+" + imText
+	}
+	return strconv.FormatFloat(re, 'f', -1, 64) + imText
+}
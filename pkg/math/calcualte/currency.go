@@ -0,0 +1,86 @@
+package calculable
+
+import (
+	"maps"
+	"math"
+)
+
+// CurrencyCalculable - получатель кодов валют, которые есть в выражении, см. Program.Currencies
+type CurrencyCalculable interface {
+	Calculable
+	SetCurrencies(codes []string)
+}
+
+// Rates - курсы валют: стоимость единицы валюты в базовой валюте, у базовой курс 1.
+// {"RUB": 1, "USD": 92.5} - 1 USD = 92.5 RUB.
+type Rates map[string]float64
+
+// WithRates - валюты в выражении: "100 USD + 50 EUR in RUB", только в режиме PrecisionFloat.
+// Единицы измерения для этого не нужны, перевод in/to работает и без WithUnits.
+// Коды валют заняты и не могут быть переменными; nil - без валют.
+func WithRates(r Rates) Option {
+	return func(c *config) error {
+		if r == nil {
+			return nil
+		}
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		c.rates = maps.Clone(r)
+		return nil
+	}
+}
+
+// Validate - коды по ISO 4217 (три заглавные латинские буквы) и положительные конечные курсы
+func (r Rates) Validate() error {
+	for code, rate := range r {
+		if !IsCurrencyCode(code) {
+			return ErrInvalidCurrency
+		}
+		if !(rate > 0) || math.IsInf(rate, 0) {
+			return ErrInvalidRate
+		}
+	}
+	return nil
+}
+
+func (r Rates) lookup(code string) (unit, bool) {
+	rate, ok := r[code]
+	if !ok {
+		return unit{}, false
+	}
+	return unit{symbol: code, factor: rate, dim: dimMoney}, true
+}
+
+// MentionsCurrency - есть ли в выражении имя, похожее на код валюты. Без таких имён курсы
+// для WithRates можно не загружать: выражение с ними и без них считается одинаково.
+func MentionsCurrency(expr string) bool {
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		if !isLetter(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for isNameRune(runes, i) {
+			i++
+		}
+		if IsCurrencyCode(string(runes[start:i])) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCurrencyCode - три заглавные латинские буквы: USD, EUR
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, char := range code {
+		if char < 'A' || char > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package calculable
+
+import (
+	"cmp"
+	"context"
+)
+
+// arithmetic - числовая система, в которой вычисляется дерево выражения
+type arithmetic[T any] interface {
+	number(n numberNode) (T, error)
+	constant(n constantNode) (T, error)
+	variable(value float64) (T, error)
+	unary(op string, a T) (T, error)
+	binary(op string, a, b T) (T, error)
+	call(name string, args []T) (T, error)
+	compare(op string, a, b T) (bool, error) // == != < <= > >=
+}
+
+// unitArithmetic - числовая система с единицами измерения, её узлы есть только в дереве режима units
+type unitArithmetic[T any] interface {
+	unit(n unitNode) (T, error)
+	convert(v, target T) (T, error)
+}
+
+// tracer - получает каждое вычисленное действие: узел, значения операндов и результат
+type tracer[T any] func(n node, operands []T, res T)
+
+// evaluate - обход дерева; отмена ctx проверяется на каждом узле,
+// чтобы долгие точные вычисления прерывались по дедлайну
+func evaluate[T any](ctx context.Context, n node, a arithmetic[T], vars Vars) (T, error) {
+	return evaluateTraced(ctx, n, a, vars, nil)
+}
+
+// evaluateTraced - evaluate с записью шагов в trace (может быть nil), шаги идут в порядке вычисления
+func evaluateTraced[T any](ctx context.Context, n node, a arithmetic[T], vars Vars, trace tracer[T]) (res T, err error) {
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	switch n := n.(type) {
+	case numberNode:
+		return a.number(n)
+	case constantNode:
+		return a.constant(n)
+	case variableNode:
+		value, exists := vars[n.name]
+		if !exists {
+			return res, &VariableError{Name: n.name, Err: ErrUndefinedVariable}
+		}
+		return a.variable(value)
+	case unaryNode:
+		operand, err := evaluateTraced(ctx, n.operand, a, vars, trace)
+		if err != nil {
+			return res, err
+		}
+		if res, err = a.unary(n.op, operand); err == nil && trace != nil {
+			trace(n, []T{operand}, res)
+		}
+		return res, err
+	case binaryNode:
+		left, err := evaluateTraced(ctx, n.left, a, vars, trace)
+		if err != nil {
+			return res, err
+		}
+		right, err := evaluateTraced(ctx, n.right, a, vars, trace)
+		if err != nil {
+			return res, err
+		}
+		if res, err = a.binary(n.op, left, right); err == nil && trace != nil {
+			trace(n, []T{left, right}, res)
+		}
+		return res, err
+	case callNode:
+		args := make([]T, len(n.args))
+		for i, arg := range n.args {
+			if args[i], err = evaluateTraced(ctx, arg, a, vars, trace); err != nil {
+				return res, err
+			}
+		}
+
+		res, err = a.call(n.name, args)
+		if err != nil {
+			return res, &FunctionError{Name: n.name, Err: err}
+		}
+		if trace != nil {
+			trace(n, args, res)
+		}
+		return res, nil
+	case unitNode:
+		ua, ok := any(a).(unitArithmetic[T])
+		if !ok {
+			return res, ErrUnitsPrecision
+		}
+		return ua.unit(n)
+	case convertNode:
+		ua, ok := any(a).(unitArithmetic[T])
+		if !ok {
+			return res, ErrUnitsPrecision
+		}
+		operand, err := evaluateTraced(ctx, n.operand, a, vars, trace)
+		if err != nil {
+			return res, err
+		}
+		// цель перевода - единица, а не действие, в шаги она не попадает
+		target, err := evaluateTraced(ctx, n.target, a, vars, nil)
+		if err != nil {
+			return res, err
+		}
+		if res, err = ua.convert(operand, target); err == nil && trace != nil {
+			trace(n, []T{operand}, res)
+		}
+		return res, err
+	case percentNode:
+		operands := make([]T, 1, 2)
+		if operands[0], err = evaluateTraced(ctx, n.left, a, vars, trace); err != nil {
+			return res, err
+		}
+		if n.right != nil {
+			right, err := evaluateTraced(ctx, n.right, a, vars, trace)
+			if err != nil {
+				return res, err
+			}
+			operands = append(operands, right)
+		}
+		if res, err = percent(a, n.op, operands[0], operands[len(operands)-1]); err == nil && trace != nil {
+			trace(n, operands, res)
+		}
+		return res, err
+	case logicNode:
+		return evaluateLogic(ctx, n, a, vars, trace)
+	case conditionalNode:
+		return evaluateConditional(ctx, n, a, vars, trace)
+	default:
+		return res, ErrUnknownOperator
+	}
+}
+
+// floatArithmetic - операторы и функции берутся из registry
+type floatArithmetic struct {
+	registry *Registry
+}
+
+func (floatArithmetic) number(n numberNode) (float64, error) {
+	return n.value, nil
+}
+
+func (floatArithmetic) constant(n constantNode) (float64, error) {
+	return n.value, nil
+}
+
+func (floatArithmetic) variable(value float64) (float64, error) {
+	return value, nil
+}
+
+func (f floatArithmetic) unary(op string, a float64) (float64, error) {
+	operation, exists := f.registry.unary[op]
+	if !exists {
+		return 0, ErrUnknownOperator
+	}
+	return finite(operation([]float64{a}))
+}
+
+func (f floatArithmetic) binary(op string, a, b float64) (float64, error) {
+	operation, exists := f.registry.binary[op]
+	if !exists {
+		return 0, ErrUnknownOperator
+	}
+	return finite(operation([]float64{a, b}))
+}
+
+func (f floatArithmetic) call(name string, args []float64) (float64, error) {
+	return finite(f.registry.functions[name].apply(args))
+}
+
+func (floatArithmetic) compare(op string, a, b float64) (bool, error) {
+	return compareOrdered(op, cmp.Compare(a, b))
+}
@@ -0,0 +1,331 @@
+package calculable
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ограничения на размер результата в точном режиме: показатель степени
+	// и длина числителя и знаменателя в битах после каждой операции
+	maxExactExponent = 1 << 14
+	maxExactBits     = 1 << 20
+)
+
+type ratArithmetic struct{}
+
+func (ratArithmetic) number(n numberNode) (*big.Rat, error) {
+	// 1e-1000000 разобрался бы во float64 как 0, а здесь потребовал бы огромного знаменателя
+	if exp, err := exponentOf(n.text); err != nil || exp > maxExactExponent || exp < -maxExactExponent {
+		return nil, ErrExponentTooLarge
+	}
+	if r, ok := new(big.Rat).SetString(n.text); ok {
+		return r, nil
+	}
+	return new(big.Rat).SetFloat64(n.value), nil
+}
+
+func (ratArithmetic) constant(n constantNode) (*big.Rat, error) {
+	return nil, fmt.Errorf("%w: %s", ErrInexact, n.name)
+}
+
+// variable - значения переменных приходят как float64, берём их кратчайшую десятичную запись: 0.1 -> 1/10
+func (ratArithmetic) variable(value float64) (*big.Rat, error) {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+	return r, nil
+}
+
+func (ratArithmetic) unary(op string, a *big.Rat) (*big.Rat, error) {
+	switch op {
+	case "+":
+		return new(big.Rat).Set(a), nil
+	case "-":
+		return new(big.Rat).Neg(a), nil
+	default:
+		// оператор из Registry вычисляется только во float64
+		return nil, ErrInexact
+	}
+}
+
+// binary - результат длиннее maxExactBits даёт LimitError: произведение допустимых степеней
+// 10^16000*10^16000*... иначе вырастает до сотен тысяч цифр
+func (ratArithmetic) binary(op string, a, b *big.Rat) (*big.Rat, error) {
+	res, err := ratBinary(op, a, b)
+	if err == nil && max(res.Num().BitLen(), res.Denom().BitLen()) > maxExactBits {
+		return nil, &LimitError{Limit: LimitSize, Max: maxExactBits}
+	}
+	return res, err
+}
+
+func ratBinary(op string, a, b *big.Rat) (*big.Rat, error) {
+	switch op {
+	case "+":
+		return new(big.Rat).Add(a, b), nil
+	case "-":
+		return new(big.Rat).Sub(a, b), nil
+	case "*":
+		return new(big.Rat).Mul(a, b), nil
+	case "/":
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return new(big.Rat).Quo(a, b), nil
+	case "%":
+		if b.Sign() == 0 {
+			return nil, ErrModuloByZero
+		}
+		// a - b*trunc(a/b), знак совпадает со знаком делимого
+		q := new(big.Rat).SetInt(ratTrunc(new(big.Rat).Quo(a, b)))
+		return new(big.Rat).Sub(a, q.Mul(q, b)), nil
+	case "//":
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return new(big.Rat).SetInt(ratFloor(new(big.Rat).Quo(a, b))), nil
+	case "^":
+		return ratPow(a, b)
+	default:
+		return nil, ErrInexact
+	}
+}
+
+func (ratArithmetic) compare(op string, a, b *big.Rat) (bool, error) {
+	return compareOrdered(op, a.Cmp(b))
+}
+
+func (ratArithmetic) call(name string, args []*big.Rat) (*big.Rat, error) {
+	fn, exists := exactFunctions[name]
+	if !exists {
+		return nil, ErrInexact
+	}
+	return fn(args)
+}
+
+// ratPow - точное возведение только в целую степень
+func ratPow(a, b *big.Rat) (*big.Rat, error) {
+	if !b.IsInt() {
+		if a.Sign() < 0 {
+			return nil, ErrNegativeBasePower
+		}
+		return nil, ErrInexact
+	}
+
+	exp := b.Num()
+	if !exp.IsInt64() || exp.Int64() > maxExactExponent || exp.Int64() < -maxExactExponent {
+		return nil, ErrExponentTooLarge
+	}
+	n := exp.Int64()
+
+	if a.Sign() == 0 {
+		if n < 0 {
+			return nil, ErrDivisionByZero
+		}
+		if n == 0 {
+			return big.NewRat(1, 1), nil
+		}
+		return new(big.Rat), nil
+	}
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	if int64(max(a.Num().BitLen(), a.Denom().BitLen()))*abs > maxExactBits {
+		return nil, ErrExponentTooLarge
+	}
+
+	e := big.NewInt(abs)
+	num := new(big.Int).Exp(a.Num(), e, nil)
+	den := new(big.Int).Exp(a.Denom(), e, nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+var exactFunctions = map[string]func(args []*big.Rat) (*big.Rat, error){
+	"abs":   func(args []*big.Rat) (*big.Rat, error) { return new(big.Rat).Abs(args[0]), nil },
+	"floor": func(args []*big.Rat) (*big.Rat, error) { return new(big.Rat).SetInt(ratFloor(args[0])), nil },
+	"ceil":  func(args []*big.Rat) (*big.Rat, error) { return new(big.Rat).SetInt(ratCeil(args[0])), nil },
+	"trunc": func(args []*big.Rat) (*big.Rat, error) { return new(big.Rat).SetInt(ratTrunc(args[0])), nil },
+	"round": ratRound,
+	"sqrt":  ratSqrt,
+	"min":   func(args []*big.Rat) (*big.Rat, error) { return ratFold(args, -1), nil },
+	"max":   func(args []*big.Rat) (*big.Rat, error) { return ratFold(args, 1), nil },
+}
+
+// ratRound - как и math.Round, половина округляется от нуля
+func ratRound(args []*big.Rat) (*big.Rat, error) {
+	digits := 0
+	if len(args) == 2 {
+		if !args[1].IsInt() || !args[1].Num().IsInt64() {
+			return nil, ErrFunctionDomain
+		}
+		digits = int(args[1].Num().Int64())
+	}
+	if digits > MaxScale || digits < -MaxScale {
+		return nil, ErrFunctionDomain
+	}
+
+	scaled := new(big.Rat).SetInt(roundScaled(args[0], digits, RoundHalfUp))
+	return scaled.Quo(scaled, pow10(digits)), nil
+}
+
+// ratSqrt - точный корень существует только у квадратов рациональных чисел
+func ratSqrt(args []*big.Rat) (*big.Rat, error) {
+	x := args[0]
+	if x.Sign() < 0 {
+		return nil, ErrFunctionDomain
+	}
+
+	num, okNum := intSqrt(x.Num())
+	den, okDen := intSqrt(x.Denom())
+	if !okNum || !okDen {
+		return nil, ErrInexact
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+func intSqrt(x *big.Int) (*big.Int, bool) {
+	root := new(big.Int).Sqrt(x)
+	return root, new(big.Int).Mul(root, root).Cmp(x) == 0
+}
+
+// ratFold - sign = -1 ищет минимум, sign = 1 максимум
+func ratFold(args []*big.Rat, sign int) *big.Rat {
+	res := args[0]
+	for _, arg := range args[1:] {
+		if arg.Cmp(res) == sign {
+			res = arg
+		}
+	}
+	return new(big.Rat).Set(res)
+}
+
+// знаменатель big.Rat всегда положителен, поэтому Div (евклидово деление) округляет вниз
+func ratFloor(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func ratCeil(r *big.Rat) *big.Int {
+	return new(big.Int).Neg(ratFloor(new(big.Rat).Neg(r)))
+}
+
+func ratTrunc(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+func pow10(exp int) *big.Rat {
+	abs := exp
+	if abs < 0 {
+		abs = -abs
+	}
+
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs)), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+// RoundingMode - способ округления при выводе точного результата
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half-even" // банковское: 2.5 -> 2, 3.5 -> 4
+	RoundHalfUp   RoundingMode = "half-up"   // половина от нуля: 2.5 -> 3, -2.5 -> -3
+	RoundHalfDown RoundingMode = "half-down" // половина к нулю: 2.5 -> 2
+	RoundUp       RoundingMode = "up"        // от нуля
+	RoundTruncate RoundingMode = "truncate"  // к нулю
+	RoundCeiling  RoundingMode = "ceiling"   // к +бесконечности
+	RoundFloor    RoundingMode = "floor"     // к -бесконечности
+)
+
+// ParseRoundingMode - пустая строка означает RoundHalfEven
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch m := RoundingMode(s); m {
+	case "":
+		return RoundHalfEven, nil
+	case RoundHalfEven, RoundHalfUp, RoundHalfDown, RoundUp, RoundTruncate, RoundCeiling, RoundFloor:
+		return m, nil
+	default:
+		return "", ErrUnknownRounding
+	}
+}
+
+const (
+	// AutoScale - вывести результат без лишних нулей, но не длиннее maxAutoScale знаков после запятой
+	AutoScale    = -1
+	MaxScale     = 100
+	maxAutoScale = 20
+)
+
+// FormatDecimal - десятичная запись r ровно со scale знаками после запятой, либо AutoScale
+func FormatDecimal(r *big.Rat, scale int, mode RoundingMode) string {
+	if scale >= 0 {
+		return formatScaled(roundScaled(r, scale, mode), scale)
+	}
+
+	s := formatScaled(roundScaled(r, maxAutoScale, mode), maxAutoScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// roundScaled - r * 10^scale, округлённое до целого
+func roundScaled(r *big.Rat, scale int, mode RoundingMode) *big.Int {
+	scaled := new(big.Rat).Mul(r, pow10(scale))
+	num, den := scaled.Num(), scaled.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	negative := num.Sign() < 0
+	// сравниваем отброшенную часть с половиной: 2*|rem| и den
+	twice := new(big.Int).Abs(rem)
+	half := twice.Lsh(twice, 1).Cmp(den)
+
+	var away bool // округлять ли от нуля
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundTruncate:
+		away = false
+	case RoundCeiling:
+		away = !negative
+	case RoundFloor:
+		away = negative
+	case RoundHalfUp:
+		away = half >= 0
+	case RoundHalfDown:
+		away = half > 0
+	default:
+		away = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+
+	if away {
+		if negative {
+			return q.Sub(q, big.NewInt(1))
+		}
+		return q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+func formatScaled(q *big.Int, scale int) string {
+	digits := new(big.Int).Abs(q).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if q.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
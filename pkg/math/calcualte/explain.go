@@ -0,0 +1,102 @@
+package calculable
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+)
+
+// Step - одно действие вычисления: оператор или функция над уже вычисленными операндами
+type Step struct {
+	Expression string   // подвыражение нормализованного выражения, без внешних скобок: "1 + 2"
+	Operator   string   // оператор или имя функции
+	Operands   []string // значения операндов
+	Result     string
+	// Interpretation - как понята процентная операция: "200 + 200 * 10 / 100"; у остальных пусто
+	Interpretation string
+}
+
+// Explain - Run с записью шагов в порядке вычисления: операнды раньше действия над ними.
+// Константы не сворачиваются, поэтому в шагах есть и 2*pi. Значения в режиме exact
+// записываются FormatDecimal с AutoScale, результат в c - так же, как у Run.
+func (p *Program) Explain(ctx context.Context, c Calculable, vars Vars) ([]Step, error) {
+	steps := []Step{}
+	if err := p.run(ctx, c, vars, &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// ExplainExpression - Explain для выражения c.GetExpression(), разбор тот же, что у CalculateExpression
+func ExplainExpression(ctx context.Context, c Calculable, vars Vars, opts ...Option) ([]Step, error) {
+	p, err := Compile(c.GetExpression(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Explain(ctx, c, vars)
+}
+
+// recorder - tracer, который дописывает шаги в steps; nil, если шаги не нужны
+func recorder[T any](expr string, steps *[]Step, format func(T) string) tracer[T] {
+	if steps == nil {
+		return nil
+	}
+
+	runes := []rune(expr)
+	return func(n node, operands []T, res T) {
+		step := Step{Operands: make([]string, len(operands)), Result: format(res)}
+		for i, operand := range operands {
+			step.Operands[i] = format(operand)
+		}
+
+		var s span
+		switch n := n.(type) {
+		case unaryNode:
+			step.Operator, s = n.op, n.span
+		case binaryNode:
+			step.Operator, s = n.op, n.span
+		case callNode:
+			step.Operator, s = n.name, n.span
+		case convertNode:
+			step.Operator, s = n.op, n.span
+		case percentNode:
+			step.Operator, s = n.op, n.span
+			step.Interpretation = interpretPercent(n.op, step.Operands)
+		case logicNode:
+			step.Operator, s = n.op, n.span
+			step.Result = formatBoolean(step.Result)
+			formatBooleanOperands(step.Operands, n.left, n.right)
+		case conditionalNode:
+			step.Operator, s = n.op, n.span
+			formatBooleanOperands(step.Operands, n.cond, n.then)
+			if t, _ := typeOf(n.then); t == ValueBoolean {
+				step.Result = formatBoolean(step.Result)
+			}
+		}
+		step.Expression = string(runes[s.start:s.end])
+
+		*steps = append(*steps, step)
+	}
+}
+
+// formatBoolean - значение логической операции, записанное числом: "1" - "true", "0" - "false"
+func formatBoolean(value string) string {
+	return strconv.FormatBool(value != "0")
+}
+
+// formatBooleanOperands - логические операнды записываются как true и false, числовые остаются числами
+func formatBooleanOperands(operands []string, nodes ...node) {
+	for i := range operands {
+		if t, _ := typeOf(nodes[i]); t == ValueBoolean {
+			operands[i] = formatBoolean(operands[i])
+		}
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatRat(r *big.Rat) string {
+	return FormatDecimal(r, AutoScale, RoundHalfEven)
+}
@@ -0,0 +1,150 @@
+package calculable
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Notation - запись числа в FormatNumber
+type Notation string
+
+const (
+	NotationPlain       Notation = "plain"       // 12345.6
+	NotationScientific  Notation = "scientific"  // 1.23456e4, мантисса в [1, 10)
+	NotationEngineering Notation = "engineering" // 12.3456e3, порядок кратен 3
+)
+
+// ParseNotation - пустая строка означает NotationPlain
+func ParseNotation(s string) (Notation, error) {
+	switch n := Notation(s); n {
+	case "":
+		return NotationPlain, nil
+	case NotationPlain, NotationScientific, NotationEngineering:
+		return n, nil
+	default:
+		return "", ErrUnknownNotation
+	}
+}
+
+// NumberFormat - вид результата для человека. Digits задаёт значащие цифры,
+// иначе Decimals - знаки после запятой (у мантиссы в научной записи) или AutoScale.
+type NumberFormat struct {
+	Digits   int // от 1 до MaxScale, 0 - использовать Decimals
+	Decimals int // от 0 до MaxScale или AutoScale
+	Rounding RoundingMode
+	Notation Notation
+	Grouping bool   // разделять тысячи: 1,234,567.8
+	Locale   Locale // разделители: 1 234 567,8 с LocaleRussian
+}
+
+// FormatNumber - запись r по формату f. Округление - по f.Rounding, как у FormatDecimal:
+// FormatNumber(2/3, {Digits: 3}) = "0.667", FormatNumber(123456, {Digits: 2, Notation: NotationScientific}) = "1.2e5".
+func FormatNumber(r *big.Rat, f NumberFormat) string {
+	exp := f.exponent(r)
+	m := shiftDecimal(r, -exp)
+	s := f.round(m)
+
+	// округление могло добавить разряд (9.99 -> 10.0), тогда значение - степень десяти
+	// и повторное округление с новым порядком точное
+	if rounded, ok := new(big.Rat).SetString(s); ok && rounded.Sign() != 0 {
+		whole := shiftDecimal(rounded, exp)
+		if newExp := f.exponent(whole); newExp != exp || decimalExponent(rounded) != decimalExponent(m) {
+			exp, m = newExp, shiftDecimal(whole, -newExp)
+			s = f.round(m)
+		}
+	}
+
+	s = localize(s, f.Grouping, f.Locale.separators())
+	if f.Notation == NotationScientific || f.Notation == NotationEngineering {
+		s += "e" + strconv.Itoa(exp)
+	}
+	return s
+}
+
+// exponent - порядок, на который делится значение перед округлением; 0 в обычной записи
+func (f NumberFormat) exponent(r *big.Rat) int {
+	if r.Sign() == 0 {
+		return 0
+	}
+
+	switch f.Notation {
+	case NotationScientific:
+		return decimalExponent(r)
+	case NotationEngineering:
+		exp := decimalExponent(r)
+		// деление с округлением вниз, порядок -1 даёт -3
+		if exp < 0 {
+			return (exp - 2) / 3 * 3
+		}
+		return exp / 3 * 3
+	default:
+		return 0
+	}
+}
+
+// round - m с нужным числом знаков после запятой
+func (f NumberFormat) round(m *big.Rat) string {
+	decimals := f.Decimals
+	if f.Digits > 0 {
+		decimals = f.Digits - 1
+		if m.Sign() != 0 {
+			decimals -= decimalExponent(m)
+		}
+	}
+
+	if decimals >= 0 || decimals == AutoScale && f.Digits == 0 {
+		return FormatDecimal(m, decimals, f.Rounding)
+	}
+
+	// значащих цифр меньше, чем в целой части: 12345 с двумя - 12000
+	q := roundScaled(m, decimals, f.Rounding)
+	if q.Sign() == 0 {
+		return "0"
+	}
+	return q.String() + strings.Repeat("0", -decimals)
+}
+
+// decimalExponent - порядок старшей цифры r != 0: 2 для 123.4, -3 для 0.0012
+func decimalExponent(r *big.Rat) int {
+	abs := new(big.Rat).Abs(r)
+	exp := len(abs.Num().String()) - len(abs.Denom().String())
+
+	// оценка по длине числителя и знаменателя ошибается не больше чем на единицу
+	for abs.Cmp(pow10(exp)) < 0 {
+		exp--
+	}
+	for abs.Cmp(pow10(exp+1)) >= 0 {
+		exp++
+	}
+	return exp
+}
+
+// shiftDecimal - r * 10^exp
+func shiftDecimal(r *big.Rat, exp int) *big.Rat {
+	return new(big.Rat).Mul(r, pow10(exp))
+}
+
+// localize - десятичная запись с разделителями локали, тысячи разделяются при grouping
+func localize(s string, grouping bool, seps separators) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range intPart {
+		if grouping && i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteRune(seps.group)
+		}
+		b.WriteRune(digit)
+	}
+
+	if hasFrac {
+		b.WriteRune(seps.decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
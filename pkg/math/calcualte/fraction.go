@@ -0,0 +1,39 @@
+package calculable
+
+import "math/big"
+
+// Output - запись точного результата
+type Output string
+
+const (
+	OutputDecimal  Output = "decimal"  // 0.5, по умолчанию; знаки и округление - FormatDecimal
+	OutputFraction Output = "fraction" // 1/2 без округления, см. FormatFraction
+)
+
+// ParseOutput - пустая строка означает OutputDecimal
+func ParseOutput(s string) (Output, error) {
+	switch o := Output(s); o {
+	case "":
+		return OutputDecimal, nil
+	case OutputDecimal, OutputFraction:
+		return o, nil
+	default:
+		return "", ErrUnknownOutput
+	}
+}
+
+// FormatFraction - несократимая дробь "1/2", целое без знаменателя: "3", "-7/2"
+func FormatFraction(r *big.Rat) string {
+	return r.RatString()
+}
+
+// FormatMixed - смешанная дробь: 7/2 - "3 1/2", -7/2 - "-3 1/2", правильная дробь и целое - как FormatFraction
+func FormatMixed(r *big.Rat) string {
+	whole, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if whole.Sign() == 0 || rem.Sign() == 0 {
+		return FormatFraction(r)
+	}
+
+	// знак уже в целой части, QuoRem отбрасывает дробную часть к нулю
+	return whole.String() + " " + rem.Abs(rem).String() + "/" + r.Denom().String()
+}
@@ -0,0 +1,153 @@
+package calculable
+
+import (
+	"fmt"
+	"math"
+)
+
+// FunctionError - ошибка вызова функции с указанием её имени.
+// Причина (ErrUnknownFunction, ErrArgumentCount, ErrFunctionDomain) доступна через errors.Is.
+type FunctionError struct {
+	Name   string
+	Err    error
+	Detail string
+}
+
+func (e *FunctionError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s: %s", e.Err, e.Name)
+	}
+	return fmt.Sprintf("%s: %s %s", e.Err, e.Name, e.Detail)
+}
+
+func (e *FunctionError) Unwrap() error {
+	return e.Err
+}
+
+const variadic = -1
+
+type function struct {
+	minArgs, maxArgs int // maxArgs == variadic - без верхней границы
+	apply            func(args []float64) (float64, error)
+}
+
+func (f function) checkArity(name string, count int) error {
+	if count >= f.minArgs && (f.maxArgs == variadic || count <= f.maxArgs) {
+		return nil
+	}
+
+	var expects string
+	switch {
+	case f.maxArgs == variadic:
+		expects = fmt.Sprintf("at least %d", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		expects = fmt.Sprint(f.minArgs)
+	default:
+		expects = fmt.Sprintf("%d to %d", f.minArgs, f.maxArgs)
+	}
+
+	return &FunctionError{
+		Name:   name,
+		Err:    ErrArgumentCount,
+		Detail: fmt.Sprintf("expects %s, got %d", expects, count),
+	}
+}
+
+var functions = map[string]function{
+	// корни
+	"sqrt": unary(func(x float64) bool { return x >= 0 }, math.Sqrt),
+	"cbrt": unary(nil, math.Cbrt),
+
+	// тригонометрия, углы в радианах
+	"sin":   unary(nil, math.Sin),
+	"cos":   unary(nil, math.Cos),
+	"tan":   unary(nil, math.Tan),
+	"asin":  unary(inUnitRange, math.Asin),
+	"acos":  unary(inUnitRange, math.Acos),
+	"atan":  unary(nil, math.Atan),
+	"atan2": {minArgs: 2, maxArgs: 2, apply: func(args []float64) (float64, error) { return math.Atan2(args[0], args[1]), nil }},
+
+	// логарифмы и экспонента
+	"ln":    unary(positive, math.Log),
+	"log2":  unary(positive, math.Log2),
+	"log10": unary(positive, math.Log10),
+	"log":   {minArgs: 1, maxArgs: 2, apply: logarithm},
+	"exp":   unary(nil, math.Exp),
+
+	// округление
+	"abs":   unary(nil, math.Abs),
+	"floor": unary(nil, math.Floor),
+	"ceil":  unary(nil, math.Ceil),
+	"trunc": unary(nil, math.Trunc),
+	"round": {minArgs: 1, maxArgs: 2, apply: round},
+
+	"min": {minArgs: 1, maxArgs: variadic, apply: func(args []float64) (float64, error) { return fold(args, math.Min), nil }},
+	"max": {minArgs: 1, maxArgs: variadic, apply: func(args []float64) (float64, error) { return fold(args, math.Max), nil }},
+}
+
+// unary - функция одного аргумента; inDomain == nil - определена на всей прямой
+func unary(inDomain func(float64) bool, f func(float64) float64) function {
+	return function{
+		minArgs: 1,
+		maxArgs: 1,
+		apply: func(args []float64) (float64, error) {
+			if inDomain != nil && !inDomain(args[0]) {
+				return 0, ErrFunctionDomain
+			}
+			return f(args[0]), nil
+		},
+	}
+}
+
+func positive(x float64) bool {
+	return x > 0
+}
+
+func inUnitRange(x float64) bool {
+	return -1 <= x && x <= 1
+}
+
+// logarithm - log(x) по основанию 10, log(x, base) по произвольному основанию
+func logarithm(args []float64) (float64, error) {
+	x := args[0]
+	if !positive(x) {
+		return 0, ErrFunctionDomain
+	}
+	if len(args) == 1 {
+		return math.Log10(x), nil
+	}
+
+	base := args[1]
+	if !positive(base) || base == 1 {
+		return 0, ErrFunctionDomain
+	}
+	return math.Log(x) / math.Log(base), nil
+}
+
+// round - round(x) до целого, round(x, n) до n знаков после запятой (n < 0 - до десятков, сотен...).
+// |n| не больше MaxScale, как в точном режиме, иначе 10^n выходит за float64.
+func round(args []float64) (float64, error) {
+	x := args[0]
+	if len(args) == 1 {
+		return math.Round(x), nil
+	}
+
+	digits := args[1]
+	if digits != math.Trunc(digits) || math.Abs(digits) > MaxScale {
+		return 0, ErrFunctionDomain
+	}
+	scale := math.Pow(10, digits)
+	// у такого большого x нет знаков после запятой на этой позиции
+	if math.IsInf(x*scale, 0) {
+		return x, nil
+	}
+	return math.Round(x*scale) / scale, nil
+}
+
+func fold(args []float64, f func(a, b float64) float64) float64 {
+	res := args[0]
+	for _, arg := range args[1:] {
+		res = f(res, arg)
+	}
+	return res
+}
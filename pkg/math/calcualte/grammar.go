@@ -0,0 +1,88 @@
+package calculable
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+type operator struct {
+	precedence int
+	rightAssoc bool
+}
+
+// grammar - операторы, которые понимает разбор выражения; у режимов вычисления они разные
+type grammar struct {
+	binary    map[string]operator
+	unary     map[string]int // приоритет префиксного оператора
+	imaginary bool           // литералы 4i и константа i, см. complexGrammar
+	percent   bool           // постфиксный % и "of", см. percentNode
+}
+
+// floatGrammar - операторы режимов float и exact и сравнения из logicOperators.
+// Унарные операции связывают сильнее умножения, но слабее возведения в степень: -2^2 = -4
+var floatGrammar = withLogic(grammar{
+	binary: map[string]operator{
+		"+":  {precedence: 1},
+		"-":  {precedence: 1},
+		"*":  {precedence: 2},
+		"/":  {precedence: 2},
+		"%":  {precedence: 2},
+		"//": {precedence: 2},
+		"^":  {precedence: 4, rightAssoc: true},
+		// изменение в процентах слабее всех операторов: 100 -> 100 + 25 = 25
+		percentChange: {precedence: 0},
+	},
+	unary:   map[string]int{"+": 3, "-": 3},
+	percent: true,
+}, 3)
+
+func (g grammar) clone() grammar {
+	return grammar{binary: maps.Clone(g.binary), unary: maps.Clone(g.unary), imaginary: g.imaginary, percent: g.percent}
+}
+
+// matchOperator - жадный поиск: "//" важнее, чем "/"
+func (g grammar) matchOperator(runes []rune) string {
+	for size := min(g.maxOperatorLen(), len(runes)); size > 0; size-- {
+		op := string(runes[:size])
+		_, isBinary := g.binary[op]
+		_, isUnary := g.unary[op]
+		if isBinary || isUnary {
+			return op
+		}
+	}
+	return ""
+}
+
+// maxOperatorLen - самый длинный оператор, в рунах
+func (g grammar) maxOperatorLen() int {
+	longest := 0
+	for op := range g.binary {
+		longest = max(longest, utf8.RuneCountInString(op))
+	}
+	for op := range g.unary {
+		longest = max(longest, utf8.RuneCountInString(op))
+	}
+	return longest
+}
+
+// uses - есть ли char в каком-либо операторе
+func (g grammar) uses(char rune) bool {
+	for op := range g.binary {
+		if strings.ContainsRune(op, char) {
+			return true
+		}
+	}
+	for op := range g.unary {
+		if strings.ContainsRune(op, char) {
+			return true
+		}
+	}
+	return false
+}
+
+// operandStart - с чего может начинаться операнд, для ParseError.Expected
+func (g grammar) operandStart() []string {
+	return append([]string{expectNumber, expectIdentifier, "("}, slices.Sorted(maps.Keys(g.unary))...)
+}
@@ -0,0 +1,289 @@
+package calculable
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// IntegerCalculable - получатель результата в режиме PrecisionInteger.
+// Если Calculable его не реализует, в SetResult придёт ближайший float64.
+type IntegerCalculable interface {
+	Calculable
+	SetIntegerResult(*big.Int)
+}
+
+// IntType - целочисленный тип фиксированной ширины для режима PrecisionInteger
+type IntType string
+
+const (
+	Int8   IntType = "int8"
+	Int16  IntType = "int16"
+	Int32  IntType = "int32"
+	Int64  IntType = "int64"
+	Uint8  IntType = "uint8"
+	Uint16 IntType = "uint16"
+	Uint32 IntType = "uint32"
+	Uint64 IntType = "uint64"
+)
+
+var intBits = map[IntType]uint{
+	Int8: 8, Int16: 16, Int32: 32, Int64: 64,
+	Uint8: 8, Uint16: 16, Uint32: 32, Uint64: 64,
+}
+
+// ParseIntType - пустая строка означает Int64
+func ParseIntType(s string) (IntType, error) {
+	t := IntType(s)
+	if t == "" {
+		return Int64, nil
+	}
+	if _, exists := intBits[t]; !exists {
+		return "", ErrUnknownIntType
+	}
+	return t, nil
+}
+
+func (t IntType) bits() uint {
+	return intBits[t]
+}
+
+func (t IntType) signed() bool {
+	return !strings.HasPrefix(string(t), "u")
+}
+
+// bounds - минимальное и максимальное значение типа
+func (t IntType) bounds() (lo, hi *big.Int) {
+	if !t.signed() {
+		hi = new(big.Int).Lsh(big.NewInt(1), t.bits())
+		return new(big.Int), hi.Sub(hi, big.NewInt(1))
+	}
+
+	lo = new(big.Int).Lsh(big.NewInt(1), t.bits()-1)
+	hi = new(big.Int).Sub(lo, big.NewInt(1))
+	return lo.Neg(lo), hi
+}
+
+// bitPattern - недесятичный литерал в ширину типа задаёт биты в дополнительном коде, как в FormatInteger:
+// для int8 0xFF - это -1. Литерал шире типа приводится к нему по правилу overflow, как десятичный.
+func (t IntType) bitPattern(v *big.Int) *big.Int {
+	_, hi := t.bounds()
+	size := new(big.Int).Lsh(big.NewInt(1), t.bits())
+	if !t.signed() || v.Cmp(hi) <= 0 || v.Cmp(size) >= 0 {
+		return v
+	}
+	return v.Sub(v, size)
+}
+
+// Overflow - поведение при выходе результата за границы типа
+type Overflow string
+
+const (
+	OverflowWrap     Overflow = "wrap"     // по модулю 2^bits, как в Go: int8(127+1) = -128
+	OverflowSaturate Overflow = "saturate" // ближайшая граница: int8(127+1) = 127
+	OverflowError    Overflow = "error"    // ErrIntegerOverflow
+)
+
+// ParseOverflow - пустая строка означает OverflowWrap
+func ParseOverflow(s string) (Overflow, error) {
+	switch o := Overflow(s); o {
+	case "":
+		return OverflowWrap, nil
+	case OverflowWrap, OverflowSaturate, OverflowError:
+		return o, nil
+	default:
+		return "", ErrUnknownOverflow
+	}
+}
+
+// integerGrammar - операторы режима PrecisionInteger, приоритеты как в Python и C:
+// сравнения < '|' < '^' < '&' < сдвиги < '+ -' < '* / % //' < унарные. '^' здесь - исключающее ИЛИ, степени нет.
+var integerGrammar = withLogic(grammar{
+	binary: map[string]operator{
+		"|":  {precedence: 1},
+		"^":  {precedence: 2},
+		"&":  {precedence: 3},
+		"<<": {precedence: 4},
+		">>": {precedence: 4},
+		"+":  {precedence: 5},
+		"-":  {precedence: 5},
+		"*":  {precedence: 6},
+		"/":  {precedence: 6},
+		"%":  {precedence: 6},
+		"//": {precedence: 6},
+	},
+	unary: map[string]int{"+": 7, "-": 7, "~": 7},
+}, 7)
+
+// integerArithmetic - каждое значение, включая литералы и переменные, приводится к intType по правилу overflow
+type integerArithmetic struct {
+	intType  IntType
+	overflow Overflow
+}
+
+func (a integerArithmetic) number(n numberNode) (*big.Int, error) {
+	r, err := ratArithmetic{}.number(n)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%w: %s", ErrNotInteger, n.text)
+	}
+	v := new(big.Int).Set(r.Num())
+	if n.radix != 0 {
+		v = a.intType.bitPattern(v)
+	}
+	return a.fit(v)
+}
+
+func (integerArithmetic) constant(n constantNode) (*big.Int, error) {
+	return nil, fmt.Errorf("%w: %s", ErrNotInteger, n.name)
+}
+
+func (a integerArithmetic) variable(value float64) (*big.Int, error) {
+	if math.IsInf(value, 0) || math.IsNaN(value) || value != math.Trunc(value) {
+		return nil, ErrNotInteger
+	}
+	v, _ := big.NewFloat(value).Int(nil)
+	return a.fit(v)
+}
+
+func (a integerArithmetic) unary(op string, v *big.Int) (*big.Int, error) {
+	switch op {
+	case "+":
+		return new(big.Int).Set(v), nil
+	case "-":
+		return a.fit(new(big.Int).Neg(v))
+	case "~":
+		// у знаковых ~x = -x-1 всегда в границах, у беззнаковых инвертируются только bits разрядов
+		if a.intType.signed() {
+			return new(big.Int).Not(v), nil
+		}
+		_, hi := a.intType.bounds()
+		return hi.Xor(hi, v), nil
+	default:
+		return nil, ErrUnknownOperator
+	}
+}
+
+func (a integerArithmetic) binary(op string, x, y *big.Int) (*big.Int, error) {
+	switch op {
+	case "+":
+		return a.fit(new(big.Int).Add(x, y))
+	case "-":
+		return a.fit(new(big.Int).Sub(x, y))
+	case "*":
+		return a.fit(new(big.Int).Mul(x, y))
+	case "/":
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// деление с отбрасыванием дробной части, как в Go; MinInt / -1 выходит за границы
+		return a.fit(new(big.Int).Quo(x, y))
+	case "%":
+		if y.Sign() == 0 {
+			return nil, ErrModuloByZero
+		}
+		return new(big.Int).Rem(x, y), nil
+	case "//":
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+		if r.Sign() != 0 && r.Sign() != y.Sign() {
+			q.Sub(q, big.NewInt(1))
+		}
+		return a.fit(q)
+	// значения уже в границах типа, а у big.Int побитовые операции в дополнительном коде
+	case "&":
+		return new(big.Int).And(x, y), nil
+	case "|":
+		return new(big.Int).Or(x, y), nil
+	case "^":
+		return new(big.Int).Xor(x, y), nil
+	case "<<", ">>":
+		if y.Sign() < 0 {
+			return nil, ErrNegativeShift
+		}
+		// сдвиг больше ширины типа даёт тот же результат, что и на bits+1, и не раздувает big.Int
+		n := a.intType.bits() + 1
+		if y.IsUint64() && y.Uint64() < uint64(n) {
+			n = uint(y.Uint64())
+		}
+		if op == ">>" {
+			return new(big.Int).Rsh(x, n), nil
+		}
+		return a.fit(new(big.Int).Lsh(x, n))
+	default:
+		return nil, ErrUnknownOperator
+	}
+}
+
+func (integerArithmetic) compare(op string, x, y *big.Int) (bool, error) {
+	return compareOrdered(op, x.Cmp(y))
+}
+
+func (a integerArithmetic) call(name string, args []*big.Int) (*big.Int, error) {
+	switch name {
+	case "abs":
+		return a.fit(new(big.Int).Abs(args[0]))
+	case "min", "max":
+		sign := -1
+		if name == "max" {
+			sign = 1
+		}
+		res := args[0]
+		for _, arg := range args[1:] {
+			if arg.Cmp(res) == sign {
+				res = arg
+			}
+		}
+		return new(big.Int).Set(res), nil
+	default:
+		return nil, ErrIntegerFunction
+	}
+}
+
+// fit - приведение точного результата к intType
+func (a integerArithmetic) fit(v *big.Int) (*big.Int, error) {
+	lo, hi := a.intType.bounds()
+	if v.Cmp(lo) >= 0 && v.Cmp(hi) <= 0 {
+		return v, nil
+	}
+
+	switch a.overflow {
+	case OverflowSaturate:
+		if v.Cmp(lo) < 0 {
+			return lo, nil
+		}
+		return hi, nil
+	case OverflowError:
+		return nil, ErrIntegerOverflow
+	default:
+		size := new(big.Int).Lsh(big.NewInt(1), a.intType.bits())
+		wrapped := new(big.Int).Mod(v, size)
+		if wrapped.Cmp(hi) > 0 {
+			wrapped.Sub(wrapped, size)
+		}
+		return wrapped, nil
+	}
+}
+
+var basePrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// FormatInteger - запись значения типа t по основанию 2, 8, 10 или 16, с префиксом 0b/0o/0x.
+// Отрицательные значения в недесятичной записи выводятся в дополнительном коде ширины типа: int8(-1) -> 0xff.
+func FormatInteger(v *big.Int, t IntType, base int) string {
+	prefix, exists := basePrefixes[base]
+	if !exists {
+		return v.String()
+	}
+
+	u := v
+	if v.Sign() < 0 {
+		u = new(big.Int).Lsh(big.NewInt(1), t.bits())
+		u.Add(u, v)
+	}
+	return prefix + u.Text(base)
+}
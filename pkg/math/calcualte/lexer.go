@@ -0,0 +1,96 @@
+package calculable
+
+import "unicode"
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenIdent
+	tokenComma
+	tokenQuestion // ? и : условного выражения
+	tokenColon
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // позиция в рунах от начала выражения
+}
+
+// tokenize - литералы чисел записываются в токены без локали, см. separators.neutral
+func tokenize(input string, g grammar, s separators) ([]token, error) {
+	runes := []rune(input)
+	tokens := make([]token, 0, len(runes)/2+1)
+
+	for i := 0; i < len(runes); {
+		char := runes[i]
+
+		switch {
+		case unicode.IsSpace(char):
+			i++
+		// операнд
+		case isDigit(char) || s.isDecimal(char):
+			start := i
+			end, err := scanNumber(runes, start, s)
+			if err != nil {
+				return nil, err
+			}
+			// мнимый литерал 4i, но не 4in
+			if g.imaginary && runeAt(runes, end) == imaginaryUnit && !isNameRune(runes, end+1) {
+				end++
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenNumber, text: s.neutral(runes[start:i]), pos: start})
+		// имя функции
+		case isLetter(char):
+			start := i
+			for ; i < len(runes) && (isLetter(runes[i]) || isDigit(runes[i])); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case char == s.list:
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case char == '?':
+			tokens = append(tokens, token{kind: tokenQuestion, text: "?", pos: i})
+			i++
+		case char == ':':
+			tokens = append(tokens, token{kind: tokenColon, text: ":", pos: i})
+			i++
+		// группировка
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		// операции
+		default:
+			op := g.matchOperator(runes[i:])
+			if op == "" {
+				return nil, newParseError(runes, ErrInvalidCharacter, i, string(char))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func isDigit(char rune) bool {
+	return '0' <= char && char <= '9'
+}
+
+func isLetter(char rune) bool {
+	return char == '_' || unicode.IsLetter(char)
+}
+
+// isNameRune - руна в позиции i продолжает имя
+func isNameRune(runes []rune, i int) bool {
+	return i < len(runes) && (isLetter(runes[i]) || isDigit(runes[i]))
+}
@@ -0,0 +1,51 @@
+package calculable
+
+import "fmt"
+
+// Limits - ограничения на размер выражения, проверяются при разборе; 0 - без ограничения,
+// кроме MaxDepth
+type Limits struct {
+	MaxLength     int // длина выражения в рунах
+	MaxTokens     int
+	MaxDepth      int // вложенность скобок, вызовов и операторов; 0 - DefaultLimits.MaxDepth
+	MaxOperations int // операторы и вызовы функций
+}
+
+// DefaultLimits - ограничения без WithLimits. Глубина ограничена всегда,
+// иначе "((((...1))))" из миллиона скобок переполнит стек рекурсивного разбора.
+var DefaultLimits = Limits{
+	MaxLength:     10_000,
+	MaxTokens:     5_000,
+	MaxDepth:      200,
+	MaxOperations: 2_000,
+}
+
+// Limit - какое из ограничений Limits превышено
+type Limit string
+
+const (
+	LimitLength     Limit = "length"
+	LimitTokens     Limit = "tokens"
+	LimitDepth      Limit = "depth"
+	LimitOperations Limit = "operations"
+	LimitSize       Limit = "size" // длина точного результата в битах, проверяется при вычислении
+)
+
+// LimitError - выражение превышает Limits, errors.Is(err, ErrTooComplex)
+type LimitError struct {
+	Limit Limit
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s exceeds %d", ErrTooComplex, e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrTooComplex
+}
+
+// exceeds - 0 означает отсутствие ограничения
+func exceeds(value, max int) bool {
+	return max > 0 && value > max
+}
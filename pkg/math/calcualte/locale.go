@@ -0,0 +1,106 @@
+package calculable
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Locale - язык записи чисел в выражении и в FormatNumber
+type Locale string
+
+const (
+	LocaleNeutral   Locale = ""   // 1234.5, аргументы через запятую, по умолчанию
+	LocaleEnglish   Locale = "en" // 1,234.5
+	LocaleRussian   Locale = "ru" // 1 234,5, аргументы через точку с запятой
+	LocaleUkrainian Locale = "uk"
+	LocaleGerman    Locale = "de" // 1.234,5
+	LocaleFrench    Locale = "fr"
+	LocaleSpanish   Locale = "es"
+	LocaleItalian   Locale = "it"
+)
+
+// separators - десятичный разделитель, разделитель тысяч и разделитель аргументов функций
+type separators struct {
+	decimal rune
+	group   rune
+	list    rune
+}
+
+var localeSeparators = map[Locale]separators{
+	LocaleNeutral:   {decimal: '.', group: ',', list: ','},
+	LocaleEnglish:   {decimal: '.', group: ',', list: ','},
+	LocaleRussian:   {decimal: ',', group: '\u00a0', list: ';'},
+	LocaleUkrainian: {decimal: ',', group: '\u00a0', list: ';'},
+	LocaleGerman:    {decimal: ',', group: '.', list: ';'},
+	LocaleFrench:    {decimal: ',', group: '\u202f', list: ';'},
+	LocaleSpanish:   {decimal: ',', group: '.', list: ';'},
+	LocaleItalian:   {decimal: ',', group: '.', list: ';'},
+}
+
+// ParseLocale - язык из тега BCP 47 без учёта региона и регистра: "ru-RU" и "ru_ru" - LocaleRussian.
+// Пустая строка означает LocaleNeutral.
+func ParseLocale(s string) (Locale, error) {
+	lang, _, _ := strings.Cut(strings.ReplaceAll(s, "_", "-"), "-")
+	l := Locale(strings.ToLower(lang))
+	if _, ok := localeSeparators[l]; !ok {
+		return "", ErrUnknownLocale
+	}
+	return l, nil
+}
+
+func (l Locale) separators() separators {
+	if s, ok := localeSeparators[l]; ok {
+		return s
+	}
+	return localeSeparators[LocaleNeutral]
+}
+
+// isDecimal - точка считается десятичным разделителем в любой локали, где она не разделяет тысячи
+func (s separators) isDecimal(char rune) bool {
+	return char == s.decimal || char == '.' && s.group != '.'
+}
+
+// isGroup - разделитель тысяч во вводе. Если он совпадает с разделителем аргументов (en),
+// то во вводе не допускается; вместо неразрывных пробелов (ru, fr) можно писать обычный.
+func (s separators) isGroup(char rune) bool {
+	if s.group == s.list {
+		return false
+	}
+	if unicode.IsSpace(s.group) {
+		return char == ' ' || char == '\u00a0' || char == '\u202f'
+	}
+	return char == s.group
+}
+
+// neutral - литерал в записи без локали: десятичный разделитель - точка, тысячи - '_'.
+// Число рун не меняется, поэтому позиции токенов остаются верными.
+func (s separators) neutral(literal []rune) string {
+	out := make([]rune, len(literal))
+	for i, char := range literal {
+		switch {
+		case s.isGroup(char):
+			out[i] = '_'
+		case s.isDecimal(char):
+			out[i] = '.'
+		default:
+			out[i] = char
+		}
+	}
+	return string(out)
+}
+
+// canonical - выражение в записи без локали: литералы из токенов, разделители аргументов - запятые,
+// остальное без изменений
+func canonical(runes []rune, tokens []token) string {
+	out := make([]rune, 0, len(runes))
+	prev := 0
+	for _, tok := range tokens {
+		if tok.kind != tokenNumber && tok.kind != tokenComma {
+			continue
+		}
+		out = append(out, runes[prev:tok.pos]...)
+		out = append(out, []rune(tok.text)...)
+		prev = tok.pos + len([]rune(tok.text))
+	}
+	return string(append(out, runes[prev:]...))
+}
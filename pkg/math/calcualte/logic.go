@@ -0,0 +1,251 @@
+package calculable
+
+import "context"
+
+// ValueType - тип результата выражения: сравнения и логические операции дают ValueBoolean
+type ValueType string
+
+const (
+	ValueNumber  ValueType = "number"
+	ValueBoolean ValueType = "boolean"
+)
+
+// BooleanCalculable - получатель результата типа ValueBoolean.
+// Если Calculable его не реализует, в SetResult придёт 1 или 0.
+type BooleanCalculable interface {
+	Calculable
+	SetBooleanResult(bool)
+}
+
+// Приоритеты ниже арифметических, как в C: a + 1 > b && c == 0 ? x : y
+const (
+	conditionalPrecedence = -5 // cond ? a : b, правоассоциативный
+	orPrecedence          = -4
+	andPrecedence         = -3
+	equalityPrecedence    = -2
+	relationalPrecedence  = -1
+
+	// lowestPrecedence - разбор полного выражения: в скобках, аргументах и ветвях условия
+	lowestPrecedence = conditionalPrecedence
+)
+
+// conditionalFunction - if(c, a, b), то же, что c ? a : b
+const conditionalFunction = "if"
+
+// logicOperators - сравнения и логические операторы, общие для всех режимов
+var logicOperators = map[string]operator{
+	"||": {precedence: orPrecedence},
+	"&&": {precedence: andPrecedence},
+	"==": {precedence: equalityPrecedence},
+	"!=": {precedence: equalityPrecedence},
+	"<":  {precedence: relationalPrecedence},
+	"<=": {precedence: relationalPrecedence},
+	">":  {precedence: relationalPrecedence},
+	">=": {precedence: relationalPrecedence},
+}
+
+// notOperator - логическое отрицание, связывает как унарный минус
+const notOperator = "!"
+
+// withLogic - грамматика режима с операторами из logicOperators и отрицанием
+func withLogic(g grammar, unaryPrecedence int) grammar {
+	for op, o := range logicOperators {
+		g.binary[op] = o
+	}
+	g.unary[notOperator] = unaryPrecedence
+	return g
+}
+
+// typeError - операнд не того типа; at - узел, в котором он встретился
+type typeError struct {
+	at span
+	op string
+}
+
+// typeOf - тип значения узла. Арифметика, функции и порядок (< >) принимают только числа,
+// == и != - операнды одного типа, ветви условия - одного типа.
+// Логические операции и условие принимают и числа: не ноль - истина.
+func typeOf(n node) (ValueType, *typeError) {
+	switch n := n.(type) {
+	case logicNode:
+		left, err := typeOf(n.left)
+		if err != nil || n.right == nil {
+			return ValueBoolean, err
+		}
+		right, err := typeOf(n.right)
+		if err != nil {
+			return "", err
+		}
+		switch n.op {
+		case "&&", "||":
+		case "==", "!=":
+			if left != right {
+				return "", &typeError{at: n.span, op: n.op}
+			}
+		default:
+			if left != ValueNumber || right != ValueNumber {
+				return "", &typeError{at: n.span, op: n.op}
+			}
+		}
+		return ValueBoolean, nil
+	case conditionalNode:
+		if _, err := typeOf(n.cond); err != nil {
+			return "", err
+		}
+		then, err := typeOf(n.then)
+		if err != nil {
+			return "", err
+		}
+		otherwise, err := typeOf(n.otherwise)
+		if err != nil {
+			return "", err
+		}
+		if then != otherwise {
+			return "", &typeError{at: n.span, op: n.op}
+		}
+		return then, nil
+	}
+
+	// остальные узлы - числа над числами
+	var err *typeError
+	walkChildren(n, func(child node) {
+		if err != nil {
+			return
+		}
+		t, childErr := typeOf(child)
+		switch {
+		case childErr != nil:
+			err = childErr
+		case t != ValueNumber:
+			err = numberExpected(n)
+		}
+	})
+	return ValueNumber, err
+}
+
+// numberExpected - место ошибки для узла, операнд которого логический
+func numberExpected(n node) *typeError {
+	switch n := n.(type) {
+	case unaryNode:
+		return &typeError{at: n.span, op: n.op}
+	case binaryNode:
+		return &typeError{at: n.span, op: n.op}
+	case callNode:
+		return &typeError{at: n.span, op: n.name}
+	case convertNode:
+		return &typeError{at: n.span, op: n.op}
+	case percentNode:
+		return &typeError{at: n.span, op: n.op}
+	default:
+		return &typeError{}
+	}
+}
+
+// truth - не ноль - истина
+func truth[T any](a arithmetic[T], v T) (bool, error) {
+	zero, err := a.number(numberNode{text: "0"})
+	if err != nil {
+		return false, err
+	}
+	return a.compare("!=", v, zero)
+}
+
+// boolean - истина и ложь в числовой системе a: 1 и 0
+func boolean[T any](a arithmetic[T], b bool) (T, error) {
+	if b {
+		return a.number(numberNode{text: "1", value: 1})
+	}
+	return a.number(numberNode{text: "0"})
+}
+
+// compareOrdered - результат сравнения по знаку cmp(a, b)
+func compareOrdered(op string, cmp int) (bool, error) {
+	holds, err := comparison(op)
+	if err != nil {
+		return false, err
+	}
+	return holds(cmp), nil
+}
+
+// comparison - проверка знака cmp(a, b) для оператора сравнения, байткод выбирает её при компиляции
+func comparison(op string) (func(cmp int) bool, error) {
+	switch op {
+	case "==":
+		return func(cmp int) bool { return cmp == 0 }, nil
+	case "!=":
+		return func(cmp int) bool { return cmp != 0 }, nil
+	case "<":
+		return func(cmp int) bool { return cmp < 0 }, nil
+	case "<=":
+		return func(cmp int) bool { return cmp <= 0 }, nil
+	case ">":
+		return func(cmp int) bool { return cmp > 0 }, nil
+	case ">=":
+		return func(cmp int) bool { return cmp >= 0 }, nil
+	default:
+		return nil, ErrUnknownOperator
+	}
+}
+
+// evaluateLogic - && и || не вычисляют правый операнд, если результат уже известен
+func evaluateLogic[T any](ctx context.Context, n logicNode, a arithmetic[T], vars Vars, trace tracer[T]) (res T, err error) {
+	left, err := evaluateTraced(ctx, n.left, a, vars, trace)
+	if err != nil {
+		return res, err
+	}
+	operands := []T{left}
+
+	var value bool
+	switch n.op {
+	case notOperator:
+		value, err = truth(a, left)
+		value = !value
+	case "&&", "||":
+		value, err = truth(a, left)
+		// false && ... и true || ... уже известны
+		if err == nil && value != (n.op == "||") {
+			var right T
+			if right, err = evaluateTraced(ctx, n.right, a, vars, trace); err != nil {
+				return res, err
+			}
+			operands = append(operands, right)
+			value, err = truth(a, right)
+		}
+	default:
+		var right T
+		if right, err = evaluateTraced(ctx, n.right, a, vars, trace); err != nil {
+			return res, err
+		}
+		operands = append(operands, right)
+		value, err = a.compare(n.op, left, right)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	if res, err = boolean(a, value); err == nil && trace != nil {
+		trace(n, operands, res)
+	}
+	return res, err
+}
+
+// evaluateConditional - вычисляется только выбранная ветвь: x > 0 ? 1/x : 0
+func evaluateConditional[T any](ctx context.Context, n conditionalNode, a arithmetic[T], vars Vars, trace tracer[T]) (res T, err error) {
+	cond, err := evaluateTraced(ctx, n.cond, a, vars, trace)
+	if err != nil {
+		return res, err
+	}
+	value, err := truth(a, cond)
+	if err != nil {
+		return res, err
+	}
+
+	branch := n.otherwise
+	if value {
+		branch = n.then
+	}
+	if res, err = evaluateTraced(ctx, branch, a, vars, trace); err == nil && trace != nil {
+		trace(n, []T{cond, res}, res)
+	}
+	return res, err
+}
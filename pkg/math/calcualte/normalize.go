@@ -0,0 +1,188 @@
+package calculable
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+// symbolReplacements - типографские знаки операций из текстовых документов
+var symbolReplacements = map[rune]rune{
+	'×': '*',
+	'·': '*',
+	'⋅': '*',
+	'∗': '*',
+	'÷': '/',
+	'∕': '/',
+	'⁄': '/',
+	'−': '-',
+	'–': '-',
+}
+
+// radicals - знаки корня и функции, которые их заменяют
+var radicals = map[rune]string{
+	'√': "sqrt",
+	'∛': "cbrt",
+}
+
+// superscripts - надстрочные цифры и знаки показателя степени: x² - x^2, 10⁻³ - 10^-3
+var superscripts = map[rune]rune{
+	'⁰': '0', '¹': '1', '²': '2', '³': '3', '⁴': '4',
+	'⁵': '5', '⁶': '6', '⁷': '7', '⁸': '8', '⁹': '9',
+	'⁺': '+', '⁻': '-',
+}
+
+// normalizer - запись нормализованного выражения с позицией каждой руны во вводе
+type normalizer struct {
+	runes  []rune
+	out    []rune
+	origin []int // origin[i] - позиция во вводе руны out[i]
+
+	grammar    grammar
+	separators separators
+	powers     bool
+}
+
+func (n *normalizer) write(pos int, s ...rune) {
+	for _, char := range s {
+		n.out = append(n.out, char)
+		n.origin = append(n.origin, pos)
+	}
+}
+
+// normalize - замена знаков, которые вставляются из документов, на синтаксис выражения:
+// × · - *, ÷ - /, − - -, √x и √(x) - sqrt(x), ∛ - cbrt, x² - x^2, неразрывные пробелы - пробел.
+// Аргумент корня без скобок - число или имя с разделителями локали: √2,25 в ru - sqrt(2,25),
+// перед ним могут стоять минус и другой корень: √-4 - sqrt(-4), √√16 - sqrt(sqrt(16)).
+// Знаки, которые входят в операторы (пользовательские из Registry), не заменяются,
+// степени нет в режиме integer: там ^ - исключающее ИЛИ.
+// Выражение только из ASCII возвращается как есть, origin тогда nil.
+func normalize(input string, cfg config) (string, []int) {
+	if isASCII(input) {
+		return input, nil
+	}
+
+	runes := []rune(input)
+	n := normalizer{
+		runes:      runes,
+		out:        make([]rune, 0, len(runes)),
+		origin:     make([]int, 0, len(runes)+1),
+		grammar:    cfg.grammar(),
+		separators: cfg.locale.separators(),
+		powers:     cfg.precision != PrecisionInteger,
+	}
+	for i := 0; i < len(runes); {
+		i = n.step(i)
+	}
+
+	// конец выражения, на него указывают ошибки "неожиданный конец"
+	n.origin = append(n.origin, len(runes))
+	return string(n.out), n.origin
+}
+
+// step - замена знака на позиции i, возвращает позицию следующего
+func (n *normalizer) step(i int) int {
+	char := n.runes[i]
+	if char > unicode.MaxASCII && n.grammar.uses(char) {
+		n.write(i, char)
+		return i + 1
+	}
+
+	if replacement, ok := symbolReplacements[char]; ok {
+		n.write(i, replacement)
+		return i + 1
+	}
+	if char > unicode.MaxASCII && unicode.IsSpace(char) {
+		n.write(i, ' ')
+		return i + 1
+	}
+
+	if _, ok := radicals[char]; ok {
+		return n.radical(i)
+	}
+
+	if _, ok := superscripts[char]; ok && n.powers {
+		n.write(i, '^')
+		for ; i < len(n.runes); i++ {
+			digit, ok := superscripts[n.runes[i]]
+			if !ok {
+				break
+			}
+			n.write(i, digit)
+		}
+		return i
+	}
+
+	n.write(i, char)
+	return i + 1
+}
+
+// radical - вызов функции корня: √(x) уже вызов, √x, √-x и √√x оборачиваются в скобки.
+// Корень без операнда даёт пустой вызов sqrt(), это ошибка разбора.
+func (n *normalizer) radical(i int) int {
+	n.write(i, []rune(radicals[n.runes[i]])...)
+	i++
+	if i < len(n.runes) && n.runes[i] == '(' {
+		return n.group(i)
+	}
+
+	n.write(i, '(')
+	for i < len(n.runes) && (n.runes[i] == '-' || symbolReplacements[n.runes[i]] == '-') {
+		n.write(i, '-')
+		i++
+	}
+	switch {
+	case i >= len(n.runes):
+	case n.runes[i] == '(':
+		i = n.group(i)
+	case radicals[n.runes[i]] != "":
+		i = n.radical(i)
+	default:
+		for i < len(n.runes) && (isLetter(n.runes[i]) || isDigit(n.runes[i]) || n.separators.isDecimal(n.runes[i])) {
+			n.write(i, n.runes[i])
+			i++
+		}
+	}
+	n.write(i, ')')
+	return i
+}
+
+// group - скобка с содержимым до парной закрывающей; без неё - до конца выражения
+func (n *normalizer) group(i int) int {
+	depth := 0
+	for i < len(n.runes) {
+		switch n.runes[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if i = n.step(i); depth == 0 {
+			break
+		}
+	}
+	return i
+}
+
+// restoreOffset - ошибка разбора нормализованного выражения с местом во вводе
+func restoreOffset(err error, input string, origin []int) error {
+	var parseErr *ParseError
+	if origin == nil || !errors.As(err, &parseErr) {
+		return err
+	}
+
+	restored := *parseErr
+	restored.Expression = input
+	restored.Offset = origin[min(parseErr.Offset, len(origin)-1)]
+	restored.ByteOffset = len(string([]rune(input)[:restored.Offset]))
+	return &restored
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
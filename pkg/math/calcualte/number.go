@@ -0,0 +1,214 @@
+package calculable
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const expectDigit = "digit"
+
+// radixPrefixes - целые литералы в других системах счисления, регистр префикса не важен
+var radixPrefixes = map[string]int{
+	"0x": 16,
+	"0b": 2,
+	"0o": 8,
+}
+
+// radixOf - основание по префиксу литерала, 10 если префикса нет
+func radixOf(runes []rune) int {
+	if len(runes) < 2 || runes[0] != '0' {
+		return 10
+	}
+	if radix, ok := radixPrefixes[strings.ToLower(string(runes[:2]))]; ok {
+		return radix
+	}
+	return 10
+}
+
+// scanNumber - конец числового литерала, начинающегося в start.
+// Литерал проверяется целиком, чтобы ошибка указывала на конкретный символ:
+// 1_000.5e-3, .5, 5., 0xFF, 0b1010, 0o17, а с разделителями локали s - 1 000,5.
+func scanNumber(runes []rune, start int, s separators) (int, error) {
+	if radix := radixOf(runes[start:]); radix != 10 {
+		return scanRadix(runes, start, radix, s)
+	}
+
+	i, intDigits, err := scanDigits(runes, start, 10)
+	if err != nil {
+		return 0, err
+	}
+	if i, err = scanGroups(runes, i, intDigits, s); err != nil {
+		return 0, err
+	}
+
+	fracDigits := 0
+	if i < len(runes) && s.isDecimal(runes[i]) {
+		if i, fracDigits, err = scanDigits(runes, i+1, 10); err != nil {
+			return 0, err
+		}
+	}
+	if intDigits+fracDigits == 0 {
+		return 0, newParseError(runes, ErrMissingDigits, start, string(runes[start]), expectDigit)
+	}
+
+	if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+		exp := i + 1
+		if exp < len(runes) && (runes[exp] == '+' || runes[exp] == '-') {
+			exp++
+		}
+
+		var expDigits int
+		if i, expDigits, err = scanDigits(runes, exp, 10); err != nil {
+			return 0, err
+		}
+		if expDigits == 0 {
+			return 0, newParseError(runes, ErrMissingExponent, exp, runeAt(runes, exp), expectDigit)
+		}
+	}
+
+	return i, checkNumberEnd(runes, i, s)
+}
+
+func scanRadix(runes []rune, start, radix int, s separators) (int, error) {
+	digitsStart := start + 2
+	i, digits, err := scanDigits(runes, digitsStart, radix)
+	if err != nil {
+		return 0, err
+	}
+	if digits == 0 {
+		return 0, newParseError(runes, ErrMissingDigits, digitsStart, runeAt(runes, digitsStart), expectDigit)
+	}
+
+	return i, checkNumberEnd(runes, i, s)
+}
+
+// scanGroups - разделители тысяч локали после целой части из digits цифр: 1 234 567 в ru, 1.234.567 в de.
+// Группы строго по три цифры; пробел, за которым нет такой группы, просто завершает число.
+func scanGroups(runes []rune, i, digits int, s separators) (int, error) {
+	for i < len(runes) && s.isGroup(runes[i]) {
+		if digits > 0 && digits <= 3 && isGroupOfThree(runes, i+1) {
+			i, digits = i+4, 3
+			continue
+		}
+		if unicode.IsSpace(runes[i]) {
+			break
+		}
+		return 0, newParseError(runes, ErrMisplacedGroup, i, string(runes[i]), expectDigit)
+	}
+	return i, nil
+}
+
+// isGroupOfThree - ровно три цифры с позиции i
+func isGroupOfThree(runes []rune, i int) bool {
+	if i+3 > len(runes) {
+		return false
+	}
+	for _, char := range runes[i : i+3] {
+		if !isDigit(char) {
+			return false
+		}
+	}
+	return i+3 == len(runes) || !isDigit(runes[i+3]) && runes[i+3] != '_'
+}
+
+// scanDigits - цифры и разделители '_' с позиции i; возвращает конец и количество цифр.
+// Для оснований кроме 10 забираются и буквы, чтобы 0b102 и 0xFG были ошибкой, а не двумя токенами.
+func scanDigits(runes []rune, i, radix int) (int, int, error) {
+	count := 0
+	for ; i < len(runes); i++ {
+		char := runes[i]
+
+		if char == '_' {
+			// разделитель допустим только между цифрами
+			if count == 0 || i+1 == len(runes) || digitValue(runes[i+1]) >= radix {
+				return 0, 0, newParseError(runes, ErrMisplacedSeparator, i, "_", expectDigit)
+			}
+			continue
+		}
+
+		if !isDigit(char) && (radix == 10 || !isASCIILetter(char)) {
+			break
+		}
+		if digitValue(char) >= radix {
+			return 0, 0, newParseError(runes, ErrInvalidDigit, i, string(char), expectDigit)
+		}
+		count++
+	}
+
+	return i, count, nil
+}
+
+// checkNumberEnd - после литерала не может идти вторая точка: 1.2.3, 1e5.5, 0x1.8
+func checkNumberEnd(runes []rune, i int, s separators) error {
+	if i >= len(runes) || !s.isDecimal(runes[i]) {
+		return nil
+	}
+	if s.isDecimal(runes[i-1]) {
+		return newParseError(runes, ErrDoubleDot, i, string(runes[i]))
+	}
+	return newParseError(runes, ErrUnexpectedDot, i, string(runes[i]))
+}
+
+func digitValue(char rune) int {
+	switch {
+	case isDigit(char):
+		return int(char - '0')
+	case 'a' <= char && char <= 'z':
+		return int(char-'a') + 10
+	case 'A' <= char && char <= 'Z':
+		return int(char-'A') + 10
+	default:
+		return math.MaxInt
+	}
+}
+
+func isASCIILetter(char rune) bool {
+	return ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z')
+}
+
+func runeAt(runes []rune, i int) string {
+	if i >= len(runes) {
+		return ""
+	}
+	return string(runes[i])
+}
+
+// parseNumber - значение литерала, уже проверенного scanNumber.
+// В text узла попадает десятичная запись без разделителей, её разбирают точные режимы.
+func parseNumber(literal string) (numberNode, error) {
+	if radix := radixOf([]rune(literal)); radix != 10 {
+		// основание 0: префикс и '_' разбираются по правилам Go
+		n, ok := new(big.Int).SetString(literal, 0)
+		if !ok {
+			return numberNode{}, ErrInvalidDigit
+		}
+		value, _ := new(big.Float).SetInt(n).Float64()
+		if math.IsInf(value, 0) {
+			return numberNode{}, ErrOverflow
+		}
+		return numberNode{text: n.String(), value: value, radix: radix}, nil
+	}
+
+	text := strings.ReplaceAll(literal, "_", "")
+	value, err := strconv.ParseFloat(text, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return numberNode{}, ErrOverflow
+	}
+	if err != nil {
+		return numberNode{}, err
+	}
+	return numberNode{text: text, value: value}, nil
+}
+
+// exponentOf - порядок десятичного литерала: 15 для 1.5e15, 0 без экспоненты
+func exponentOf(text string) (int, error) {
+	i := strings.IndexAny(text, "eE")
+	if i < 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(text[i+1:])
+}
@@ -0,0 +1,49 @@
+package calculable
+
+import "math"
+
+func div(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, ErrDivisionByZero
+	}
+	return a / b, nil
+}
+
+// mod - остаток от деления, знак совпадает со знаком делимого
+func mod(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, ErrModuloByZero
+	}
+	return math.Mod(a, b), nil
+}
+
+// floorDiv - деление с округлением вниз: 7//2 = 3, -7//2 = -4
+func floorDiv(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, ErrDivisionByZero
+	}
+	return math.Floor(a / b), nil
+}
+
+func pow(a, b float64) (float64, error) {
+	if a < 0 && b != math.Trunc(b) {
+		return 0, ErrNegativeBasePower
+	}
+	if a == 0 && b < 0 {
+		return 0, ErrDivisionByZero
+	}
+	return math.Pow(a, b), nil
+}
+
+// finite - результат операции не должен выходить за пределы float64
+func finite(res float64, err error) (float64, error) {
+	switch {
+	case err != nil:
+		return 0, err
+	case math.IsInf(res, 0):
+		return 0, ErrOverflow
+	case math.IsNaN(res):
+		return 0, ErrNotANumber
+	}
+	return res, nil
+}
@@ -0,0 +1,151 @@
+package calculable
+
+// Precision - режим вычисления
+type Precision string
+
+const (
+	PrecisionFloat   Precision = "float"   // float64, по умолчанию
+	PrecisionExact   Precision = "exact"   // рациональные числа math/big без потери точности
+	PrecisionInteger Precision = "integer" // целые фиксированной ширины, см. WithIntegerType и WithOverflow
+	PrecisionComplex Precision = "complex" // complex128, мнимая единица i и литералы 4i
+)
+
+// ParsePrecision - пустая строка означает PrecisionFloat
+func ParsePrecision(s string) (Precision, error) {
+	switch p := Precision(s); p {
+	case "":
+		return PrecisionFloat, nil
+	case PrecisionFloat, PrecisionExact, PrecisionInteger, PrecisionComplex:
+		return p, nil
+	default:
+		return "", ErrUnknownPrecision
+	}
+}
+
+type config struct {
+	precision Precision
+	intType   IntType
+	overflow  Overflow
+	registry  *Registry
+	limits    Limits
+	locale    Locale
+	units     bool
+	rates     Rates // валюты, см. WithRates
+}
+
+type Option func(*config) error
+
+func WithPrecision(p Precision) Option {
+	return func(c *config) error {
+		precision, err := ParsePrecision(string(p))
+		if err != nil {
+			return err
+		}
+		c.precision = precision
+		return nil
+	}
+}
+
+// WithIntegerType - ширина и знаковость целых в режиме PrecisionInteger, по умолчанию Int64
+func WithIntegerType(t IntType) Option {
+	return func(c *config) error {
+		intType, err := ParseIntType(string(t))
+		if err != nil {
+			return err
+		}
+		c.intType = intType
+		return nil
+	}
+}
+
+// WithOverflow - поведение при переполнении в режиме PrecisionInteger, по умолчанию OverflowWrap
+func WithOverflow(o Overflow) Option {
+	return func(c *config) error {
+		overflow, err := ParseOverflow(string(o))
+		if err != nil {
+			return err
+		}
+		c.overflow = overflow
+		return nil
+	}
+}
+
+// WithRegistry - операторы и функции выражения, по умолчанию только встроенные.
+// Пользовательские операторы действуют в режимах float и exact, функции - во всех режимах.
+func WithRegistry(r *Registry) Option {
+	return func(c *config) error {
+		if r != nil {
+			c.registry = r
+		}
+		return nil
+	}
+}
+
+// WithLimits - ограничения на размер выражения вместо DefaultLimits.
+// Глубину отключить нельзя: MaxDepth <= 0 заменяется на DefaultLimits.MaxDepth.
+func WithLimits(l Limits) Option {
+	return func(c *config) error {
+		if l.MaxDepth <= 0 {
+			l.MaxDepth = DefaultLimits.MaxDepth
+		}
+		c.limits = l
+		return nil
+	}
+}
+
+// WithLocale - десятичный разделитель, разделитель тысяч и аргументов во вводе по локали l:
+// с LocaleRussian выражение записывается как "max(3,5; 1 000)". По умолчанию LocaleNeutral.
+func WithLocale(l Locale) Option {
+	return func(c *config) error {
+		locale, err := ParseLocale(string(l))
+		if err != nil {
+			return err
+		}
+		c.locale = locale
+		return nil
+	}
+}
+
+// WithUnits - единицы измерения в выражении: "5 km + 300 m in mi", только в режиме PrecisionFloat.
+// Имена единиц в этом режиме заняты и не могут быть переменными.
+func WithUnits(enabled bool) Option {
+	return func(c *config) error {
+		c.units = enabled
+		return nil
+	}
+}
+
+func newConfig(opts []Option) (config, error) {
+	cfg := config{
+		precision: PrecisionFloat,
+		intType:   Int64,
+		overflow:  OverflowWrap,
+		registry:  builtins,
+		limits:    DefaultLimits,
+	}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return config{}, err
+		}
+	}
+	if cfg.quantities() && cfg.precision != PrecisionFloat {
+		return config{}, ErrUnitsPrecision
+	}
+	return cfg, nil
+}
+
+// quantities - вычисление с единицами измерения или валютами
+func (c config) quantities() bool {
+	return c.units || c.rates != nil
+}
+
+func (c config) grammar() grammar {
+	switch c.precision {
+	case PrecisionInteger:
+		return integerGrammar
+	case PrecisionComplex:
+		return complexGrammar
+	default:
+		return c.registry.grammar
+	}
+}
@@ -0,0 +1,65 @@
+package calculable
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError - синтаксическая ошибка с указанием места в выражении.
+// Причина (ErrMissingOperator, ErrUnclosedBracket, *FunctionError...) доступна через errors.Is и errors.As.
+type ParseError struct {
+	Err        error
+	Expression string
+	Offset     int      // позиция в рунах
+	ByteOffset int      // та же позиция в байтах UTF-8
+	Token      string   // "" - конец выражения
+	Expected   []string // что допустимо на этом месте, может быть пустым
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Caret - выражение и строка с '^' под местом ошибки:
+//
+//	1 + * 2
+//	    ^
+func (e *ParseError) Caret() string {
+	runes := []rune(e.Expression)
+
+	var pad strings.Builder
+	for _, char := range runes[:min(e.Offset, len(runes))] {
+		// табуляция сохраняется, чтобы '^' не съехал
+		if char == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+
+	return e.Expression + "\n" + pad.String() + "^"
+}
+
+// ожидаемые токены для ParseError.Expected
+const (
+	expectNumber     = "number"
+	expectIdentifier = "identifier"
+	expectOperator   = "operator"
+	expectEnd        = "end of expression"
+	expectUnit       = "unit"
+)
+
+func newParseError(runes []rune, err error, pos int, text string, expected ...string) *ParseError {
+	return &ParseError{
+		Err:        err,
+		Expression: string(runes),
+		Offset:     pos,
+		ByteOffset: len(string(runes[:pos])),
+		Token:      text,
+		Expected:   expected,
+	}
+}
@@ -0,0 +1,488 @@
+package calculable
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type parser struct {
+	grammar  grammar
+	registry *Registry // функции
+	limits   Limits
+	units    bool   // единицы измерения и перевод in/to, см. WithUnits
+	rates    Rates  // валюты, с ними перевод in/to есть и без units
+	runes    []rune // исходное выражение, для ParseError
+	tokens   []token
+	cur      int
+	depth    int // текущая вложенность parseExpression
+	ops      int // операторы и вызовы в дереве
+}
+
+func parse(input string, cfg config) (node, error) {
+	g := cfg.grammar()
+	runes := []rune(input)
+	if exceeds(len(runes), cfg.limits.MaxLength) {
+		return nil, &LimitError{Limit: LimitLength, Max: cfg.limits.MaxLength}
+	}
+	if strings.TrimSpace(input) == "" {
+		return nil, newParseError(runes, ErrEmptyExpression, 0, "", g.operandStart()...)
+	}
+
+	tokens, err := tokenize(input, g, cfg.locale.separators())
+	if err != nil {
+		return nil, err
+	}
+	// последний токен - конец выражения
+	if exceeds(len(tokens)-1, cfg.limits.MaxTokens) {
+		return nil, &LimitError{Limit: LimitTokens, Max: cfg.limits.MaxTokens}
+	}
+
+	p := parser{grammar: g, registry: cfg.registry, limits: cfg.limits, units: cfg.units, rates: cfg.rates, runes: runes, tokens: tokens}
+	if err := p.checkBrackets(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseExpression(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	// после разбора должен остаться только конец выражения
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok, expectOperator, expectEnd)
+	}
+
+	if _, err := typeOf(root); err != nil {
+		return nil, newParseError(runes, ErrTypeMismatch, err.at.start, err.op)
+	}
+
+	return root, nil
+}
+
+// checkBrackets - проверка баланса скобок до разбора,
+// чтобы несбалансированное выражение не маскировалось другими ошибками.
+func (p *parser) checkBrackets() error {
+	var open []token // незакрытые '('
+	for i, tok := range p.tokens {
+		switch tok.kind {
+		case tokenLParen:
+			open = append(open, tok)
+			// пустые скобки допустимы только у вызова функции, там их проверит арность
+			if next := p.tokens[i+1]; next.kind == tokenRParen && (i == 0 || p.tokens[i-1].kind != tokenIdent) {
+				return p.errorAt(next, ErrEmptyBrackets, p.grammar.operandStart()...)
+			}
+		case tokenRParen:
+			if len(open) == 0 {
+				return p.errorAt(tok, ErrUnopenedBracket)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+
+	if len(open) > 0 {
+		return p.errorAt(open[len(open)-1], ErrUnclosedBracket, ")")
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *parser) prev() token {
+	if p.cur < 2 {
+		return token{}
+	}
+	return p.tokens[p.cur-2]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.cur]
+	if tok.kind != tokenEOF {
+		p.cur++
+	}
+	return tok
+}
+
+// parseExpression - разбор методом precedence climbing:
+// забираем операторы, пока их приоритет не ниже minPrecedence.
+func (p *parser) parseExpression(minPrecedence int) (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if exceeds(p.depth, p.limits.MaxDepth) {
+		return nil, &LimitError{Limit: LimitDepth, Max: p.limits.MaxDepth}
+	}
+
+	start := p.peek().pos
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind == tokenIdent && tok.text == percentOf && p.grammar.percent {
+			next, err := p.parseOf(left, start, minPrecedence)
+			if err != nil || next == nil {
+				return left, err
+			}
+			left = next
+			continue
+		}
+		if tok.kind == tokenQuestion {
+			next, err := p.parseConditional(left, start, minPrecedence)
+			if err != nil || next == nil {
+				return left, err
+			}
+			left = next
+			continue
+		}
+		if tok.kind == tokenIdent && (p.units || p.rates != nil) {
+			next, err := p.parseUnitSuffix(left, start, minPrecedence)
+			if err != nil || next == nil {
+				return left, err
+			}
+			left = next
+			continue
+		}
+		if tok.kind != tokenOperator {
+			return left, nil
+		}
+		if tok.text == percentSign && p.isPostfixPercent() {
+			if percentPrecedence < minPrecedence {
+				return left, nil
+			}
+			p.next()
+			if err := p.countOperation(); err != nil {
+				return nil, err
+			}
+			left = percentNode{op: percentSign, left: left, span: span{start, p.end()}}
+			continue
+		}
+
+		op, isBinary := p.grammar.binary[tok.text]
+		if !isBinary {
+			// только префиксный оператор, как '~' в 1 ~ 2
+			return nil, p.errorAt(tok, ErrMissingOperator, expectOperator)
+		}
+		if op.precedence < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		if err := p.countOperation(); err != nil {
+			return nil, err
+		}
+
+		nextMin := op.precedence + 1
+		if op.rightAssoc {
+			nextMin = op.precedence
+		}
+
+		right, err := p.parseExpression(nextMin)
+		if err != nil {
+			return nil, err
+		}
+
+		left = combine(tok.text, left, right, span{start, p.end()})
+	}
+}
+
+// combine - узел бинарного оператора; процент справа от + и - считается от левого операнда
+func combine(op string, left, right node, s span) node {
+	if _, isLogic := logicOperators[op]; isLogic {
+		return logicNode{op: op, left: left, right: right, span: s}
+	}
+	if op == percentChange {
+		return percentNode{op: op, left: left, right: right, span: s}
+	}
+	if pct, ok := right.(percentNode); ok && pct.op == percentSign && (op == "+" || op == "-") {
+		return percentNode{op: op + percentSign, left: left, right: pct.left, span: s}
+	}
+	return binaryNode{op: op, left: left, right: right, span: s}
+}
+
+// isPostfixPercent - % без операнда после него - процент, а не остаток: "10%", "10% + 1", "50% of 80".
+// Остаток от отрицательного числа пишется в скобках: 7 % (-3).
+func (p *parser) isPostfixPercent() bool {
+	if !p.grammar.percent {
+		return false
+	}
+
+	switch next := p.tokens[p.cur+1]; next.kind {
+	case tokenEOF, tokenRParen, tokenComma:
+		return true
+	case tokenOperator:
+		_, isBinary := p.grammar.binary[next.text]
+		return isBinary
+	case tokenIdent:
+		return next.text == percentOf || conversions[next.text] && (p.units || p.rates != nil)
+	default:
+		return false
+	}
+}
+
+// parseConditional - "cond ? a : b"; nil без ошибки - оператор слабее minPrecedence
+func (p *parser) parseConditional(cond node, start, minPrecedence int) (node, error) {
+	if conditionalPrecedence < minPrecedence {
+		return nil, nil
+	}
+	p.next()
+	if err := p.countOperation(); err != nil {
+		return nil, err
+	}
+
+	then, err := p.parseExpression(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.kind != tokenColon {
+		return nil, p.errorAt(tok, ErrUnclosedConditional, ":")
+	}
+	// правоассоциативный: a ? b : c ? d : e - это a ? b : (c ? d : e)
+	otherwise, err := p.parseExpression(conditionalPrecedence)
+	if err != nil {
+		return nil, err
+	}
+	return conditionalNode{op: "?", cond: cond, then: then, otherwise: otherwise, span: span{start, p.end()}}, nil
+}
+
+// parseOf - "50% of 80"; nil без ошибки - оператор слабее minPrecedence
+func (p *parser) parseOf(left node, start, minPrecedence int) (node, error) {
+	if ofPrecedence < minPrecedence {
+		return nil, nil
+	}
+	tok := p.next()
+	pct, ok := left.(percentNode)
+	if !ok || pct.op != percentSign {
+		return nil, p.errorAt(tok, ErrExpectedPercent, percentSign)
+	}
+	if err := p.countOperation(); err != nil {
+		return nil, err
+	}
+
+	right, err := p.parseExpression(ofPrecedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	return percentNode{op: percentOf, left: pct.left, right: right, span: span{start, p.end()}}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		literal, imaginary := tok.text, false
+		if p.grammar.imaginary {
+			literal, imaginary = strings.CutSuffix(literal, imaginaryUnit)
+		}
+		n, err := parseNumber(literal)
+		if err != nil {
+			return nil, p.errorAt(tok, err)
+		}
+		n.imaginary = imaginary
+		return n, nil
+	case tokenLParen:
+		return p.parseGroup()
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			p.next()
+			return p.parseCall(tok)
+		}
+
+		if p.grammar.imaginary && tok.text == imaginaryUnit {
+			return constantNode{name: imaginaryUnit}, nil
+		}
+		if u, isUnit := p.lookupUnit(tok.text); isUnit {
+			return unitNode{name: tok.text, unit: u}, nil
+		}
+		n, err := identifier(tok.text, p.registry)
+		if err != nil {
+			return nil, p.errorAt(tok, err, "(")
+		}
+		return n, nil
+	case tokenOperator:
+		if precedence, isUnary := p.grammar.unary[tok.text]; isUnary {
+			if err := p.countOperation(); err != nil {
+				return nil, err
+			}
+			operand, err := p.parseExpression(precedence)
+			if err != nil {
+				return nil, err
+			}
+			if tok.text == notOperator {
+				return logicNode{op: notOperator, left: operand, span: span{tok.pos, p.end()}}, nil
+			}
+			// -10% - процент от -10, чтобы работали -10% of 50 и 200 + -10%
+			if pct, ok := operand.(percentNode); ok && pct.op == percentSign {
+				end := pct.end - 1
+				for end > tok.pos && p.runes[end-1] == ' ' {
+					end--
+				}
+				pct.left = unaryNode{op: tok.text, operand: pct.left, span: span{tok.pos, end}}
+				pct.start = tok.pos
+				return pct, nil
+			}
+			return unaryNode{op: tok.text, operand: operand, span: span{tok.pos, p.end()}}, nil
+		}
+
+		// в начале выражения или сразу после '(' операнд обязателен
+		if p.prev().kind != tokenOperator {
+			return nil, p.errorAt(tok, ErrStartsWithOperator, p.grammar.operandStart()...)
+		}
+		return nil, p.errorAt(tok, ErrConsecutiveOperators, p.grammar.operandStart()...)
+	case tokenQuestion, tokenColon:
+		if p.prev().kind != tokenOperator {
+			return nil, p.errorAt(tok, ErrStartsWithOperator, p.grammar.operandStart()...)
+		}
+		return nil, p.errorAt(tok, ErrConsecutiveOperators, p.grammar.operandStart()...)
+	default:
+		return nil, p.errorAt(tok, ErrEndsWithOperator, p.grammar.operandStart()...)
+	}
+}
+
+// parseUnitSuffix - имя после операнда в режиме units: единица после числа ("9.81 m") или перевод ("in mi").
+// nil без ошибки - имя сюда не относится или оператор слабее minPrecedence.
+func (p *parser) parseUnitSuffix(left node, start, minPrecedence int) (node, error) {
+	tok := p.peek()
+	if conversions[tok.text] {
+		// перевод слабее всех операторов: 5 km + 300 m in mi - (5 km + 300 m) in mi
+		if minPrecedence > 0 {
+			return nil, nil
+		}
+		p.next()
+		if err := p.countOperation(); err != nil {
+			return nil, err
+		}
+
+		targetTok := p.peek()
+		target, err := p.parseExpression(1)
+		if err != nil {
+			return nil, err
+		}
+		if !isUnitExpression(target) {
+			return nil, p.errorAt(targetTok, ErrExpectedUnit, expectUnit)
+		}
+		return convertNode{op: tok.text, operand: left, target: target, span: span{start, p.end()}}, nil
+	}
+
+	if _, isUnit := p.lookupUnit(tok.text); !isUnit || p.tokens[p.cur+1].kind == tokenLParen || unitPrecedence < minPrecedence {
+		return nil, nil
+	}
+	if err := p.countOperation(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseExpression(unitPrecedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: "*", left: left, right: right, span: span{start, p.end()}}, nil
+}
+
+// lookupUnit - единица из справочника в режиме units или валюта из WithRates
+func (p *parser) lookupUnit(name string) (unit, bool) {
+	if p.units {
+		if u, ok := lookupUnit(name); ok {
+			return u, true
+		}
+	}
+	return p.rates.lookup(name)
+}
+
+func (p *parser) parseGroup() (node, error) {
+	inner, err := p.parseExpression(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.next(); tok.kind != tokenRParen {
+		return nil, p.unexpected(tok, expectOperator, ")")
+	}
+
+	return inner, nil
+}
+
+// parseCall - разбор вызова после "name(", арность проверяется сразу
+func (p *parser) parseCall(name token) (node, error) {
+	fn, exists := p.registry.functions[name.text]
+	if cf, ok := complexFunctions[name.text]; ok && p.grammar.imaginary {
+		fn, exists = cf.function, true
+	}
+	if name.text == conditionalFunction {
+		fn, exists = function{minArgs: 3, maxArgs: 3}, true
+	}
+	if !exists {
+		return nil, p.errorAt(name, &FunctionError{Name: name.text, Err: ErrUnknownFunction})
+	}
+	if err := p.countOperation(); err != nil {
+		return nil, err
+	}
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn.checkArity(name.text, len(args)); err != nil {
+		return nil, p.errorAt(name, err)
+	}
+
+	if name.text == conditionalFunction {
+		return conditionalNode{op: name.text, cond: args[0], then: args[1], otherwise: args[2], span: span{name.pos, p.end()}}, nil
+	}
+	return callNode{name: name.text, args: args, span: span{name.pos, p.end()}}, nil
+}
+
+func (p *parser) parseArguments() ([]node, error) {
+	var args []node
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return args, nil
+	}
+
+	for {
+		if tok := p.peek(); tok.kind == tokenComma || tok.kind == tokenRParen {
+			return nil, p.errorAt(tok, ErrEmptyArgument, p.grammar.operandStart()...)
+		}
+
+		arg, err := p.parseExpression(lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		switch tok := p.next(); tok.kind {
+		case tokenRParen:
+			return args, nil
+		case tokenComma:
+		default:
+			return nil, p.errorAt(tok, ErrMissingOperator, expectOperator, ",", ")")
+		}
+	}
+}
+
+// end - позиция сразу за последним разобранным токеном
+func (p *parser) end() int {
+	last := p.tokens[p.cur-1]
+	return last.pos + utf8.RuneCountInString(last.text)
+}
+
+func (p *parser) countOperation() error {
+	p.ops++
+	if exceeds(p.ops, p.limits.MaxOperations) {
+		return &LimitError{Limit: LimitOperations, Max: p.limits.MaxOperations}
+	}
+	return nil
+}
+
+// unexpected - ошибка для лишнего токена после законченного операнда
+func (p *parser) unexpected(tok token, expected ...string) error {
+	if tok.kind == tokenComma {
+		return p.errorAt(tok, ErrUnexpectedComma, expected...)
+	}
+	return p.errorAt(tok, ErrMissingOperator, expected...)
+}
+
+func (p *parser) errorAt(tok token, err error, expected ...string) error {
+	return newParseError(p.runes, err, tok.pos, tok.text, expected...)
+}
@@ -0,0 +1,128 @@
+package calculable
+
+import "fmt"
+
+// Процентные операции режимов float, exact и complex, как у настольного калькулятора:
+// 10% - 0.1, 200 + 10% - 220, 50% of 80 - 40, 100 -> 125 - 25 (изменение в процентах).
+// В режиме integer % - только остаток от деления.
+const (
+	percentSign   = "%"
+	percentAdd    = "+%" // a + b%: b процентов от a
+	percentSub    = "-%"
+	percentOf     = "of"
+	percentChange = "->"
+)
+
+const (
+	percentPrecedence = 3 // постфиксный %, как у унарных: -10% - это -(10%)
+	ofPrecedence      = 2 // как у умножения: 50% of 80 + 1 = 41
+)
+
+// percent - процентная операция через действия arithmetic, чтобы работать в любой числовой системе:
+// в режиме exact 15% of 0.1 точно, с единицами 10% of 5 km = 0.5 km
+func percent[T any](a arithmetic[T], op string, x, y T) (T, error) {
+	var res T
+	hundred, err := a.number(numberNode{text: "100", value: 100})
+	if err != nil {
+		return res, err
+	}
+
+	switch op {
+	case percentSign:
+		return a.binary("/", x, hundred)
+	case percentAdd, percentSub:
+		share, err := percent(a, percentOf, y, x)
+		if err != nil {
+			return res, err
+		}
+		return a.binary(op[:1], x, share)
+	case percentOf:
+		product, err := a.binary("*", x, y)
+		if err != nil {
+			return res, err
+		}
+		return a.binary("/", product, hundred)
+	case percentChange:
+		diff, err := a.binary("-", y, x)
+		if err != nil {
+			return res, err
+		}
+		ratio, err := a.binary("/", diff, x)
+		if err != nil {
+			return res, err
+		}
+		return a.binary("*", ratio, hundred)
+	default:
+		return res, ErrUnknownOperator
+	}
+}
+
+// interpretPercent - как понята процентная операция, для Step.Interpretation
+func interpretPercent(op string, operands []string) string {
+	switch op {
+	case percentSign:
+		return fmt.Sprintf("%s / 100", operands[0])
+	case percentAdd, percentSub:
+		return fmt.Sprintf("%s %s %s * %s / 100", operands[0], op[:1], operands[0], operands[1])
+	case percentOf:
+		return fmt.Sprintf("%s * %s / 100", operands[0], operands[1])
+	case percentChange:
+		return fmt.Sprintf("(%s - %s) / %s * 100", operands[1], operands[0], operands[0])
+	default:
+		return ""
+	}
+}
+
+// floatPercent - процентная операция для байткода, те же действия, что у percent.
+// Операторы выбираются при компиляции, вычисление не выделяет память.
+func floatPercent(op string) func(args []float64) (float64, error) {
+	mul, div := scalarOperation("*"), scalarOperation("/")
+
+	switch op {
+	case percentSign:
+		return func(args []float64) (float64, error) { return div(args[0], 100) }
+	case percentAdd, percentSub:
+		apply := scalarOperation(op[:1])
+		return func(args []float64) (float64, error) {
+			product, err := mul(args[1], args[0])
+			if err != nil {
+				return 0, err
+			}
+			share, err := div(product, 100)
+			if err != nil {
+				return 0, err
+			}
+			return apply(args[0], share)
+		}
+	case percentOf:
+		return func(args []float64) (float64, error) {
+			product, err := mul(args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			return div(product, 100)
+		}
+	case percentChange:
+		sub := scalarOperation("-")
+		return func(args []float64) (float64, error) {
+			diff, err := sub(args[1], args[0])
+			if err != nil {
+				return 0, err
+			}
+			ratio, err := div(diff, args[0])
+			if err != nil {
+				return 0, err
+			}
+			return mul(ratio, 100)
+		}
+	default:
+		return func([]float64) (float64, error) { return 0, ErrUnknownOperator }
+	}
+}
+
+// scalarOperation - встроенный оператор с проверкой результата, как floatArithmetic.binary.
+// Встроенные операторы в Registry не заменяются, поэтому их можно взять из floatOperations.
+func scalarOperation(op string) func(a, b float64) (float64, error) {
+	apply := floatOperations[op]
+	return func(a, b float64) (float64, error) { return finite(apply(a, b)) }
+}
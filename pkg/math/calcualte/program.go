@@ -0,0 +1,328 @@
+package calculable
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+)
+
+// Program - разобранное выражение. Неизменяемо после Compile,
+// поэтому одну программу можно вычислять из нескольких горутин с разными переменными.
+type Program struct {
+	expr       string
+	normalized string // expr после normalize, его разбирает parse
+	canonical  string // normalized без локали, см. Canonical
+	root       node
+	typ        ValueType
+	cfg        config
+	variables  []string
+	currencies []string  // валюты выражения, см. WithRates
+	quantities bool      // в выражении есть единицы, валюты или перевод in/to
+	code       *bytecode // только для PrecisionFloat без единиц
+}
+
+// Compile - разбор выражения один раз; режим вычисления задаётся опциями и не меняется
+func Compile(expr string, opts ...Option) (*Program, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, origin := normalize(expr, cfg)
+	root, err := parse(normalized, cfg)
+	if err != nil {
+		return nil, restoreOffset(err, expr, origin)
+	}
+
+	p := &Program{
+		expr:       expr,
+		normalized: normalized,
+		canonical:  normalized,
+		root:       root,
+		cfg:        cfg,
+		variables:  variablesOf(root),
+		currencies: currenciesOf(root),
+		quantities: hasQuantities(root),
+	}
+	// типы проверены при разборе
+	p.typ, _ = typeOf(root)
+	if cfg.locale != LocaleNeutral {
+		// выражение уже разобрано, повторная разбивка на токены ошибок не даёт
+		tokens, _ := tokenize(normalized, cfg.grammar(), cfg.locale.separators())
+		p.canonical = canonical([]rune(normalized), tokens)
+	}
+	// с WithUnits и WithRates выражение без единиц и валют считается как обычное
+	if cfg.precision == PrecisionFloat && !p.quantities {
+		p.code = compileBytecode(root, p.variables, cfg.registry)
+	}
+	return p, nil
+}
+
+func (p *Program) Expression() string {
+	return p.expr
+}
+
+// Normalized - выражение, в котором знаки из документов заменены синтаксисом выражения:
+// "√16 × 2²" - "sqrt(16) * 2^2". Позиции в ParseError указывают на исходную запись.
+func (p *Program) Normalized() string {
+	return p.normalized
+}
+
+// Canonical - нормализованное выражение в записи без локали: "3,5 + 1 000,25" с LocaleRussian - "3.5 + 1_000.25".
+// Разбирается без WithLocale и даёт тот же результат.
+func (p *Program) Canonical() string {
+	return p.canonical
+}
+
+// Currencies - коды валют выражения по алфавиту, без повторов: по ним видно, нужны ли курсы
+func (p *Program) Currencies() []string {
+	return slices.Clone(p.currencies)
+}
+
+// Type - тип результата: ValueBoolean у сравнений и логических операций, иначе ValueNumber
+func (p *Program) Type() ValueType {
+	return p.typ
+}
+
+// Variables - имена переменных выражения по алфавиту, без повторов
+func (p *Program) Variables() []string {
+	return slices.Clone(p.variables)
+}
+
+// Eval - вычисление с переменными vars (может быть nil).
+// В режимах exact и integer возвращается ближайший float64, полный результат отдаёт Run.
+// Результат типа ValueBoolean - 1 или 0.
+// В режиме complex результат с мнимой частью даёт ErrNotReal.
+func (p *Program) Eval(vars Vars) (float64, error) {
+	return p.EvalContext(context.Background(), vars)
+}
+
+// EvalContext - Eval, который прерывается с ошибкой ctx.Err() при отмене ctx
+func (p *Program) EvalContext(ctx context.Context, vars Vars) (float64, error) {
+	return p.eval(ctx, vars, nil)
+}
+
+// eval - steps != nil включает запись шагов, см. Explain
+func (p *Program) eval(ctx context.Context, vars Vars, steps *[]Step) (float64, error) {
+	if p.quantities {
+		result, err := p.evalQuantity(ctx, vars, steps)
+		return result.display(), err
+	}
+
+	switch p.cfg.precision {
+	case PrecisionExact:
+		result, err := p.evalExact(ctx, vars, steps)
+		if err != nil {
+			return 0, err
+		}
+		approx, _ := result.Float64()
+		if math.IsInf(approx, 0) {
+			return 0, ErrOverflow
+		}
+		return approx, nil
+	case PrecisionInteger:
+		result, err := p.evalInteger(ctx, vars, steps)
+		if err != nil {
+			return 0, err
+		}
+		approx, _ := new(big.Float).SetInt(result).Float64()
+		return approx, nil
+	case PrecisionComplex:
+		result, err := p.evalComplex(ctx, vars, steps)
+		if err != nil {
+			return 0, err
+		}
+		if imag(result) != 0 {
+			return 0, ErrNotReal
+		}
+		return real(result), nil
+	default:
+		return p.evalFloat(ctx, vars, steps)
+	}
+}
+
+// EvalValues - вычисление без поиска переменных по имени: values идут в порядке Variables().
+// Для пакетной обработки в режиме float это самый быстрый путь, контекст здесь не проверяется:
+// время вычисления и так ограничено Limits.MaxOperations.
+func (p *Program) EvalValues(values []float64) (float64, error) {
+	if len(values) != len(p.variables) {
+		return 0, fmt.Errorf("%w: expects %d, got %d", ErrValueCount, len(p.variables), len(values))
+	}
+	if p.code != nil {
+		return p.code.run(context.Background(), nil, values)
+	}
+
+	vars := make(Vars, len(values))
+	for i, name := range p.variables {
+		vars[name] = values[i]
+	}
+	return p.Eval(vars)
+}
+
+// Run - вычисление с записью результата в c. Точный, целочисленный, комплексный и логический результаты
+// передаются через ExactCalculable, IntegerCalculable, ComplexCalculable и BooleanCalculable, если c их реализует.
+func (p *Program) Run(c Calculable, vars Vars) error {
+	return p.RunContext(context.Background(), c, vars)
+}
+
+// RunContext - Run, который прерывается с ошибкой ctx.Err() при отмене ctx
+func (p *Program) RunContext(ctx context.Context, c Calculable, vars Vars) error {
+	return p.run(ctx, c, vars, nil)
+}
+
+func (p *Program) run(ctx context.Context, c Calculable, vars Vars, steps *[]Step) error {
+	if nc, ok := c.(NormalizedCalculable); ok {
+		nc.SetNormalizedExpression(p.normalized)
+	}
+	if cc, ok := c.(CanonicalCalculable); ok {
+		cc.SetCanonicalExpression(p.canonical)
+	}
+
+	if cc, ok := c.(CurrencyCalculable); ok {
+		cc.SetCurrencies(slices.Clone(p.currencies))
+	}
+
+	if bc, ok := c.(BooleanCalculable); ok && p.typ == ValueBoolean {
+		result, err := p.eval(ctx, vars, steps)
+		if err != nil {
+			return err
+		}
+		bc.SetBooleanResult(result != 0)
+		return nil
+	}
+
+	if qc, ok := c.(QuantityCalculable); ok && p.quantities {
+		result, err := p.evalQuantity(ctx, vars, steps)
+		if err != nil {
+			return err
+		}
+		qc.SetQuantityResult(result.display(), result.unit.String())
+		return nil
+	}
+
+	switch p.cfg.precision {
+	case PrecisionExact:
+		if ec, ok := c.(ExactCalculable); ok {
+			result, err := p.evalExact(ctx, vars, steps)
+			if err != nil {
+				return err
+			}
+			ec.SetExactResult(result)
+			return nil
+		}
+	case PrecisionInteger:
+		if ic, ok := c.(IntegerCalculable); ok {
+			result, err := p.evalInteger(ctx, vars, steps)
+			if err != nil {
+				return err
+			}
+			ic.SetIntegerResult(result)
+			return nil
+		}
+	case PrecisionComplex:
+		if cc, ok := c.(ComplexCalculable); ok {
+			result, err := p.evalComplex(ctx, vars, steps)
+			if err != nil {
+				return err
+			}
+			cc.SetComplexResult(result)
+			return nil
+		}
+	}
+
+	result, err := p.eval(ctx, vars, steps)
+	if err != nil {
+		return err
+	}
+	c.SetResult(result)
+	return nil
+}
+
+// evalFloat - байткод, а для записи шагов обход дерева без свёртки констант: результат тот же до бита
+func (p *Program) evalFloat(ctx context.Context, vars Vars, steps *[]Step) (float64, error) {
+	if err := vars.validate(p.cfg.registry); err != nil {
+		return 0, err
+	}
+	if steps == nil {
+		return p.code.run(ctx, vars, nil)
+	}
+	return evaluateTraced(ctx, p.root, floatArithmetic{registry: p.cfg.registry}, vars, recorder(p.normalized, steps, formatFloat))
+}
+
+func (p *Program) evalExact(ctx context.Context, vars Vars, steps *[]Step) (*big.Rat, error) {
+	if err := vars.validate(p.cfg.registry); err != nil {
+		return nil, err
+	}
+	return evaluateTraced(ctx, p.root, ratArithmetic{}, vars, recorder(p.normalized, steps, formatRat))
+}
+
+func (p *Program) evalInteger(ctx context.Context, vars Vars, steps *[]Step) (*big.Int, error) {
+	if err := vars.validate(p.cfg.registry); err != nil {
+		return nil, err
+	}
+	a := integerArithmetic{intType: p.cfg.intType, overflow: p.cfg.overflow}
+	return evaluateTraced(ctx, p.root, a, vars, recorder(p.normalized, steps, (*big.Int).String))
+}
+
+// evalComplex - i в этом режиме константа, переменная с таким именем не принимается
+func (p *Program) evalComplex(ctx context.Context, vars Vars, steps *[]Step) (complex128, error) {
+	if err := vars.validate(p.cfg.registry); err != nil {
+		return 0, err
+	}
+	if _, exists := vars[imaginaryUnit]; exists {
+		return 0, &VariableError{Name: imaginaryUnit, Err: ErrInvalidVariableName}
+	}
+	a := complexArithmetic{registry: p.cfg.registry}
+	return evaluateTraced(ctx, p.root, a, vars, recorder(p.normalized, steps, FormatComplex))
+}
+
+// evalQuantity - имена единиц в режиме units и коды валют заняты, переменная с таким именем не принимается
+func (p *Program) evalQuantity(ctx context.Context, vars Vars, steps *[]Step) (quantity, error) {
+	if err := vars.validate(p.cfg.registry); err != nil {
+		return quantity{}, err
+	}
+	for name := range vars {
+		_, isUnit := lookupUnit(name)
+		if _, isCurrency := p.cfg.rates[name]; isUnit && p.cfg.units || isCurrency || conversions[name] {
+			return quantity{}, &VariableError{Name: name, Err: ErrInvalidVariableName}
+		}
+	}
+	a := quantityArithmetic{registry: p.cfg.registry}
+	return evaluateTraced(ctx, p.root, a, vars, recorder(p.normalized, steps, formatQuantity))
+}
+
+// hasQuantities - только такие выражения считаются с размерностями
+func hasQuantities(root node) bool {
+	found := false
+	walk(root, func(n node) {
+		switch n.(type) {
+		case unitNode, convertNode:
+			found = true
+		}
+	})
+	return found
+}
+
+func currenciesOf(root node) []string {
+	var codes []string
+	walk(root, func(n node) {
+		if u, ok := n.(unitNode); ok && u.unit.dim == dimMoney && !slices.Contains(codes, u.name) {
+			codes = append(codes, u.name)
+		}
+	})
+	slices.Sort(codes)
+	return codes
+}
+
+func variablesOf(root node) []string {
+	var names []string
+	walk(root, func(n node) {
+		if v, ok := n.(variableNode); ok && !slices.Contains(names, v.name) {
+			names = append(names, v.name)
+		}
+	})
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,117 @@
+package calculable
+
+import (
+	"fmt"
+	"maps"
+	"unicode"
+)
+
+// Variadic - Function.MaxArgs без верхней границы
+const Variadic = variadic
+
+// Operator - пользовательский оператор режима float.
+// В режиме exact его вычисление даёт ErrInexact, в режиме integer он недоступен.
+type Operator struct {
+	Symbol     string
+	Arity      int  // 2 - бинарный инфиксный, 1 - унарный префиксный
+	Precedence int  // от 1; у встроенных: сравнения и логические ниже 0, -> 0, + - 1, * / % // of 2, унарные и постфиксный % 3, ^ 4
+	RightAssoc bool // только для бинарных: a op b op c = a op (b op c)
+	Apply      func(args []float64) (float64, error)
+}
+
+// Function - пользовательская функция; в режиме exact даёт ErrInexact, в integer - ErrIntegerFunction
+type Function struct {
+	Name    string
+	MinArgs int
+	MaxArgs int // Variadic - без верхней границы
+	Apply   func(args []float64) (float64, error)
+}
+
+// Registry - операторы и функции, доступные выражениям: встроенные и добавленные через Register*.
+// Регистрировать нужно до вычислений, одновременная регистрация и вычисление не безопасны.
+// Apply должен быть чистым (константные подвыражения вычисляются ещё в Compile)
+// и не должен сохранять или изменять args.
+type Registry struct {
+	grammar   grammar
+	binary    map[string]func(args []float64) (float64, error)
+	unary     map[string]func(args []float64) (float64, error)
+	functions map[string]function
+}
+
+// builtins - реестр по умолчанию, только встроенные операторы и функции
+var builtins = NewRegistry()
+
+func NewRegistry() *Registry {
+	r := &Registry{
+		grammar:   floatGrammar.clone(),
+		binary:    make(map[string]func(args []float64) (float64, error), len(floatOperations)),
+		unary:     make(map[string]func(args []float64) (float64, error), len(unaryOperations)),
+		functions: maps.Clone(functions),
+	}
+	for op, apply := range floatOperations {
+		r.binary[op] = func(args []float64) (float64, error) { return apply(args[0], args[1]) }
+	}
+	for op, apply := range unaryOperations {
+		r.unary[op] = func(args []float64) (float64, error) { return apply(args[0]), nil }
+	}
+	return r
+}
+
+func (r *Registry) RegisterOperator(op Operator) error {
+	if !isOperatorSymbol(op.Symbol) || op.Precedence < 1 || op.Apply == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidOperator, op.Symbol)
+	}
+
+	switch op.Arity {
+	case 2:
+		if _, exists := r.grammar.binary[op.Symbol]; exists {
+			return fmt.Errorf("%w: %q", ErrOperatorExists, op.Symbol)
+		}
+		r.grammar.binary[op.Symbol] = operator{precedence: op.Precedence, rightAssoc: op.RightAssoc}
+		r.binary[op.Symbol] = op.Apply
+	case 1:
+		if _, exists := r.grammar.unary[op.Symbol]; exists {
+			return fmt.Errorf("%w: %q", ErrOperatorExists, op.Symbol)
+		}
+		r.grammar.unary[op.Symbol] = op.Precedence
+		r.unary[op.Symbol] = op.Apply
+	default:
+		return fmt.Errorf("%w: %q has arity %d", ErrInvalidOperator, op.Symbol, op.Arity)
+	}
+	return nil
+}
+
+func (r *Registry) RegisterFunction(fn Function) error {
+	if !isIdentifier(fn.Name) || fn.Apply == nil || fn.MinArgs < 0 ||
+		(fn.MaxArgs != Variadic && fn.MaxArgs < fn.MinArgs) {
+		return fmt.Errorf("%w: %q", ErrInvalidFunction, fn.Name)
+	}
+	if r.isReserved(fn.Name) {
+		return fmt.Errorf("%w: %q", ErrFunctionExists, fn.Name)
+	}
+
+	r.functions[fn.Name] = function{minArgs: fn.MinArgs, maxArgs: fn.MaxArgs, apply: fn.Apply}
+	return nil
+}
+
+// isReserved - имя занято константой или функцией и не может быть переменной
+func (r *Registry) isReserved(name string) bool {
+	_, isConst := constants[name]
+	_, isFunc := r.functions[name]
+	return isConst || isFunc || name == conditionalFunction
+}
+
+// isOperatorSymbol - только знаки, которые не разбираются как число, имя, скобка,
+// разделитель аргументов в какой-либо локали или условное выражение
+func isOperatorSymbol(symbol string) bool {
+	for _, char := range symbol {
+		if !unicode.IsPunct(char) && !unicode.IsSymbol(char) {
+			return false
+		}
+		switch char {
+		case '(', ')', ',', ';', '.', '_', '?', ':':
+			return false
+		}
+	}
+	return symbol != ""
+}
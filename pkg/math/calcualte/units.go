@@ -0,0 +1,426 @@
+package calculable
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// QuantityCalculable - получатель результата с единицей в режиме WithUnits: 5.3 и "km".
+// Если Calculable его не реализует, в SetResult придёт значение в единице результата.
+type QuantityCalculable interface {
+	Calculable
+	SetQuantityResult(value float64, unit string)
+}
+
+// dimension - степени основных величин СИ: длина, масса, время, ток, температура, количество вещества,
+// сила света, и деньги - для валют из WithRates
+type dimension [8]int
+
+func (d dimension) add(other dimension, sign int) dimension {
+	for i := range d {
+		d[i] += sign * other[i]
+	}
+	return d
+}
+
+var (
+	dimLength      = dimension{1, 0, 0, 0, 0, 0, 0}
+	dimMass        = dimension{0, 1, 0, 0, 0, 0, 0}
+	dimTime        = dimension{0, 0, 1, 0, 0, 0, 0}
+	dimCurrent     = dimension{0, 0, 0, 1, 0, 0, 0}
+	dimTemperature = dimension{0, 0, 0, 0, 1, 0, 0}
+	dimAmount      = dimension{0, 0, 0, 0, 0, 1, 0}
+	dimLuminosity  = dimension{0, 0, 0, 0, 0, 0, 1}
+	dimMoney       = dimension{0, 0, 0, 0, 0, 0, 0, 1}
+
+	dimArea     = dimension{2, 0, 0, 0, 0, 0, 0}
+	dimVolume   = dimension{3, 0, 0, 0, 0, 0, 0}
+	dimSpeed    = dimension{1, 0, -1, 0, 0, 0, 0}
+	dimForce    = dimension{1, 1, -2, 0, 0, 0, 0}
+	dimPressure = dimension{-1, 1, -2, 0, 0, 0, 0}
+	dimEnergy   = dimension{2, 1, -2, 0, 0, 0, 0}
+	dimPower    = dimension{2, 1, -3, 0, 0, 0, 0}
+	dimCharge   = dimension{0, 0, 1, 1, 0, 0, 0}
+	dimVoltage  = dimension{2, 1, -3, -1, 0, 0, 0}
+	dimOhm      = dimension{2, 1, -3, -2, 0, 0, 0}
+	dimFreq     = dimension{0, 0, -1, 0, 0, 0, 0}
+)
+
+// unit - единица измерения: factor - значение одной единицы в основных единицах СИ (м, кг, с, ...)
+type unit struct {
+	symbol string
+	factor float64
+	dim    dimension
+}
+
+type unitDef struct {
+	factor     float64
+	dim        dimension
+	prefixable bool // принимает приставки СИ: km, ms, mg
+}
+
+// units - справочник единиц. Шкалы со смещением (°C, °F) не поддерживаются: у них нет общего множителя.
+// Дюйм записывается как inch, потому что in - оператор перевода.
+var units = map[string]unitDef{
+	// основные единицы СИ, килограмм - это приставка k к грамму
+	"m":   {factor: 1, dim: dimLength, prefixable: true},
+	"g":   {factor: 1e-3, dim: dimMass, prefixable: true},
+	"s":   {factor: 1, dim: dimTime, prefixable: true},
+	"A":   {factor: 1, dim: dimCurrent, prefixable: true},
+	"K":   {factor: 1, dim: dimTemperature, prefixable: true},
+	"mol": {factor: 1, dim: dimAmount, prefixable: true},
+	"cd":  {factor: 1, dim: dimLuminosity, prefixable: true},
+
+	// производные единицы СИ
+	"Hz":  {factor: 1, dim: dimFreq, prefixable: true},
+	"N":   {factor: 1, dim: dimForce, prefixable: true},
+	"Pa":  {factor: 1, dim: dimPressure, prefixable: true},
+	"J":   {factor: 1, dim: dimEnergy, prefixable: true},
+	"W":   {factor: 1, dim: dimPower, prefixable: true},
+	"C":   {factor: 1, dim: dimCharge, prefixable: true},
+	"V":   {factor: 1, dim: dimVoltage, prefixable: true},
+	"ohm": {factor: 1, dim: dimOhm, prefixable: true},
+	"L":   {factor: 1e-3, dim: dimVolume, prefixable: true},
+
+	// внесистемные
+	"min": {factor: 60, dim: dimTime},
+	"h":   {factor: 3600, dim: dimTime},
+	"d":   {factor: 86400, dim: dimTime},
+	"t":   {factor: 1000, dim: dimMass},
+	"ha":  {factor: 1e4, dim: dimArea},
+	"bar": {factor: 1e5, dim: dimPressure, prefixable: true},
+	"kmh": {factor: 1000.0 / 3600, dim: dimSpeed},
+
+	// английские и американские
+	"inch": {factor: 0.0254, dim: dimLength},
+	"ft":   {factor: 0.3048, dim: dimLength},
+	"yd":   {factor: 0.9144, dim: dimLength},
+	"mi":   {factor: 1609.344, dim: dimLength},
+	"nmi":  {factor: 1852, dim: dimLength},
+	"oz":   {factor: 0.028349523125, dim: dimMass},
+	"lb":   {factor: 0.45359237, dim: dimMass},
+	"gal":  {factor: 3.785411784e-3, dim: dimVolume},
+	"mph":  {factor: 0.44704, dim: dimSpeed},
+	"kn":   {factor: 1852.0 / 3600, dim: dimSpeed},
+	"lbf":  {factor: 4.4482216152605, dim: dimForce},
+	"psi":  {factor: 6894.757293168361, dim: dimPressure},
+	"hp":   {factor: 745.69987158227022, dim: dimPower},
+	"cal":  {factor: 4.184, dim: dimEnergy, prefixable: true},
+}
+
+// prefixes - приставки СИ, µ и u - одно и то же
+var prefixes = map[string]float64{
+	"p": 1e-12, "n": 1e-9, "u": 1e-6, "µ": 1e-6, "m": 1e-3, "c": 1e-2, "d": 1e-1,
+	"h": 1e2, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12,
+}
+
+// conversions - операторы перевода в единицу: "5 km in mi", "100 km/h to m/s"
+var conversions = map[string]bool{"in": true, "to": true}
+
+// unitPrecedence - приоритет числа перед единицей: сильнее * и /, слабее ^: 9.81 m/s^2 - (9.81 m) / s^2
+const unitPrecedence = 3
+
+// lookupUnit - единица по имени из справочника или с приставкой: "km", "µs"; точное совпадение важнее: "cd", "min"
+func lookupUnit(name string) (unit, bool) {
+	if def, ok := units[name]; ok {
+		return unit{symbol: name, factor: def.factor, dim: def.dim}, true
+	}
+	for prefix, factor := range prefixes {
+		base, ok := strings.CutPrefix(name, prefix)
+		if def, exists := units[base]; ok && exists && def.prefixable {
+			return unit{symbol: name, factor: factor * def.factor, dim: def.dim}, true
+		}
+	}
+	return unit{}, false
+}
+
+// unitPower - множитель составной единицы: s^-2 в m/s^2
+type unitPower struct {
+	unit
+	power int
+}
+
+// compound - составная единица в порядке появления; пустая - безразмерная величина
+type compound []unitPower
+
+// mul - произведение единиц, sign = -1 - частное
+func (c compound) mul(other compound, sign int) compound {
+	res := slices.Clone(c)
+	for _, up := range other {
+		i := slices.IndexFunc(res, func(r unitPower) bool { return r.symbol == up.symbol })
+		if i < 0 {
+			res = append(res, unitPower{unit: up.unit, power: sign * up.power})
+			continue
+		}
+		res[i].power += sign * up.power
+	}
+	return slices.DeleteFunc(res, func(r unitPower) bool { return r.power == 0 })
+}
+
+func (c compound) factor() float64 {
+	f := 1.0
+	for _, up := range c {
+		f *= math.Pow(up.factor, float64(up.power))
+	}
+	return f
+}
+
+// String - запись, которую разбирает режим units: "kg*m/s^2", "m/(kg*s)", "1/s"
+func (c compound) String() string {
+	var num, den []string
+	for _, up := range c {
+		text := up.symbol
+		if p := max(up.power, -up.power); p != 1 {
+			text += "^" + strconv.Itoa(p)
+		}
+		if up.power > 0 {
+			num = append(num, text)
+		} else {
+			den = append(den, text)
+		}
+	}
+
+	res := strings.Join(num, "*")
+	switch {
+	case len(den) == 0:
+		return res
+	case res == "":
+		res = "1"
+	}
+	if len(den) == 1 {
+		return res + "/" + den[0]
+	}
+	return res + "/(" + strings.Join(den, "*") + ")"
+}
+
+// quantity - значение величины в основных единицах СИ; unit - единица, в которой показывается результат
+type quantity struct {
+	value float64
+	dim   dimension
+	unit  compound
+}
+
+// display - значение в единице результата: 5300 м с единицей km - 5.3
+func (q quantity) display() float64 {
+	return q.value / q.unit.factor()
+}
+
+func (q quantity) dimensionless() bool {
+	return q.dim == dimension{}
+}
+
+// FormatQuantity - значение и единица через пробел: "5.3 km"; без единицы - как у режима float
+func FormatQuantity(value float64, unit string) string {
+	if unit == "" {
+		return formatFloat(value)
+	}
+	return formatFloat(value) + " " + unit
+}
+
+func formatQuantity(q quantity) string {
+	return FormatQuantity(q.display(), q.unit.String())
+}
+
+// unitFunctions - функции, результат которых в единице первого аргумента: floor(5.7 km) - 5 km.
+// Остальные аргументы round безразмерные, у min и max - той же размерности, что первый.
+var unitFunctions = map[string]bool{
+	"abs": true, "floor": true, "ceil": true, "trunc": true, "round": true, "min": true, "max": true,
+}
+
+// quantityArithmetic - режим float с единицами измерения, см. WithUnits
+type quantityArithmetic struct {
+	registry *Registry
+}
+
+func (quantityArithmetic) number(n numberNode) (quantity, error) {
+	return quantity{value: n.value}, nil
+}
+
+func (quantityArithmetic) constant(n constantNode) (quantity, error) {
+	return quantity{value: n.value}, nil
+}
+
+func (quantityArithmetic) variable(value float64) (quantity, error) {
+	return quantity{value: value}, nil
+}
+
+func (quantityArithmetic) unit(n unitNode) (quantity, error) {
+	return quantity{value: n.unit.factor, dim: n.unit.dim, unit: compound{{unit: n.unit, power: 1}}}, nil
+}
+
+// convert - та же величина в единице target; target - значение одной такой единицы
+func (quantityArithmetic) convert(q, target quantity) (quantity, error) {
+	if q.dim != target.dim {
+		return quantity{}, ErrDimensionMismatch
+	}
+	q.unit = target.unit
+	return q, nil
+}
+
+func (a quantityArithmetic) unary(op string, q quantity) (quantity, error) {
+	operation, exists := a.registry.unary[op]
+	if !exists {
+		return quantity{}, ErrUnknownOperator
+	}
+	if !q.dimensionless() && op != "+" && op != "-" {
+		return quantity{}, ErrDimensionMismatch
+	}
+
+	value, err := finite(operation([]float64{q.value}))
+	q.value = value
+	return q, err
+}
+
+func (a quantityArithmetic) binary(op string, l, r quantity) (quantity, error) {
+	operation, exists := a.registry.binary[op]
+	if !exists {
+		return quantity{}, ErrUnknownOperator
+	}
+
+	res := quantity{dim: l.dim, unit: l.unit}
+	switch op {
+	case "+", "-", "%":
+		// слагаемые одной размерности, результат в единице левого: 5 km + 300 m - 5.3 km
+		if l.dim != r.dim {
+			return quantity{}, ErrDimensionMismatch
+		}
+		if len(res.unit) == 0 {
+			res.unit = r.unit
+		}
+	case "//":
+		if l.dim != r.dim {
+			return quantity{}, ErrDimensionMismatch
+		}
+		res = quantity{}
+	case "*":
+		res = quantity{dim: l.dim.add(r.dim, 1), unit: l.unit.mul(r.unit, 1)}
+	case "/":
+		res = quantity{dim: l.dim.add(r.dim, -1), unit: l.unit.mul(r.unit, -1)}
+	case "^":
+		return power(l, r)
+	default:
+		// пользовательские операторы - только над безразмерными величинами
+		if !l.dimensionless() || !r.dimensionless() {
+			return quantity{}, ErrDimensionMismatch
+		}
+	}
+
+	value, err := finite(operation([]float64{l.value, r.value}))
+	res.value = value
+	return res, err
+}
+
+// power - показатель безразмерный; величина с единицей возводится в целую степень
+func power(base, exp quantity) (quantity, error) {
+	if !exp.dimensionless() {
+		return quantity{}, ErrDimensionMismatch
+	}
+	if base.dimensionless() && len(base.unit) == 0 {
+		value, err := finite(pow(base.value, exp.value))
+		return quantity{value: value}, err
+	}
+	return root(base, exp.value, 1)
+}
+
+// root - base^(k/n) при целом k/n: m^2 в степени 1/2 - m, s в степени 1/2 - ErrDimensionMismatch
+func root(base quantity, k float64, n int) (quantity, error) {
+	exp := k / float64(n)
+	if k != math.Trunc(k) {
+		return quantity{}, ErrDimensionMismatch
+	}
+
+	res := quantity{dim: base.dim, unit: slices.Clone(base.unit)}
+	for i := range res.dim {
+		if res.dim[i]*int(k)%n != 0 {
+			return quantity{}, ErrDimensionMismatch
+		}
+		res.dim[i] = res.dim[i] * int(k) / n
+	}
+	for i := range res.unit {
+		if res.unit[i].power*int(k)%n != 0 {
+			return quantity{}, ErrDimensionMismatch
+		}
+		res.unit[i].power = res.unit[i].power * int(k) / n
+	}
+	res.unit = slices.DeleteFunc(res.unit, func(r unitPower) bool { return r.power == 0 })
+
+	value, err := finite(pow(base.value, exp))
+	res.value = value
+	return res, err
+}
+
+// compare - сравниваются величины одной размерности: 1 mi > 1 km
+func (quantityArithmetic) compare(op string, l, r quantity) (bool, error) {
+	if l.dim != r.dim {
+		return false, ErrDimensionMismatch
+	}
+	return compareOrdered(op, cmp.Compare(l.value, r.value))
+}
+
+func (a quantityArithmetic) call(name string, args []quantity) (quantity, error) {
+	switch {
+	case name == "sqrt" && !args[0].dimensionless():
+		return root(args[0], 1, 2)
+	case name == "cbrt" && !args[0].dimensionless():
+		return root(args[0], 1, 3)
+	case unitFunctions[name]:
+		return a.callInUnit(name, args)
+	}
+
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		if !arg.dimensionless() {
+			return quantity{}, ErrDimensionMismatch
+		}
+		values[i] = arg.value
+	}
+	value, err := finite(a.registry.functions[name].apply(values))
+	return quantity{value: value}, err
+}
+
+// callInUnit - функция над значениями в единице первого аргумента, см. unitFunctions
+func (a quantityArithmetic) callInUnit(name string, args []quantity) (quantity, error) {
+	first := args[0]
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		switch {
+		case i == 0 || (name == "min" || name == "max") && arg.dim == first.dim:
+			values[i] = arg.value / first.unit.factor()
+		case arg.dimensionless() && name == "round":
+			values[i] = arg.value
+		default:
+			return quantity{}, ErrDimensionMismatch
+		}
+	}
+
+	value, err := finite(a.registry.functions[name].apply(values))
+	first.value = value * first.unit.factor()
+	return first, err
+}
+
+// isUnitExpression - цель перевода состоит только из единиц, *, / и целых степеней: km/h, m/s^2
+func isUnitExpression(n node) bool {
+	switch n := n.(type) {
+	case unitNode:
+		return true
+	case binaryNode:
+		switch n.op {
+		case "*", "/":
+			return isUnitExpression(n.left) && isUnitExpression(n.right)
+		case "^":
+			return isUnitExpression(n.left) && isIntegerLiteral(n.right)
+		}
+	}
+	return false
+}
+
+func isIntegerLiteral(n node) bool {
+	if u, ok := n.(unaryNode); ok && u.op == "-" {
+		n = u.operand
+	}
+	num, ok := n.(numberNode)
+	return ok && num.value == math.Trunc(num.value)
+}
@@ -0,0 +1,60 @@
+package calculable
+
+import (
+	"fmt"
+	"math"
+)
+
+// Vars - значения переменных выражения по имени
+type Vars map[string]float64
+
+// VariableError - ошибка, связанная с конкретной переменной.
+// Причина (ErrUndefinedVariable, ErrInvalidVariableName) доступна через errors.Is.
+type VariableError struct {
+	Name string
+	Err  error
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Name)
+}
+
+func (e *VariableError) Unwrap() error {
+	return e.Err
+}
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+}
+
+// identifier - константа известна уже при разборе, переменная ищется при вычислении
+func identifier(name string, r *Registry) (node, error) {
+	if value, isConst := constants[name]; isConst {
+		return constantNode{name: name, value: value}, nil
+	}
+	if _, isFunc := r.functions[name]; isFunc || name == conditionalFunction {
+		return nil, &FunctionError{Name: name, Err: ErrMissingCallBrackets}
+	}
+	return variableNode{name: name}, nil
+}
+
+// validate - имя переменной должно быть идентификатором и не совпадать с константой или функцией
+func (v Vars) validate(r *Registry) error {
+	for name := range v {
+		if !isIdentifier(name) || r.isReserved(name) {
+			return &VariableError{Name: name, Err: ErrInvalidVariableName}
+		}
+	}
+	return nil
+}
+
+func isIdentifier(name string) bool {
+	for i, char := range name {
+		if !isLetter(char) && (i == 0 || !isDigit(char)) {
+			return false
+		}
+	}
+	return name != ""
+}